@@ -0,0 +1,310 @@
+package folib
+
+import (
+	"fmt"
+	"strings"
+)
+
+// One of the StatsClients that a BlendedStatsClient combines.
+type BlendSource struct {
+	Name   string
+	Client StatsClient
+	Weight float64
+	// Optional weights for particular stats, which override Weight.  For
+	// example, a source might be trusted for strikeouts but not for wins.
+	StatWeights map[StatID]float64
+}
+
+func (s BlendSource) weight(stat StatID) float64 {
+	if w, ok := s.StatWeights[stat]; ok {
+		return w
+	}
+	return s.Weight
+}
+
+// A StatsClient which combines several others (e.g. multiple projection
+// systems) into a weighted average.
+//
+// Sources which don't know about a player, or don't have a particular stat,
+// are left out of the average for that player or stat, and the remaining
+// weights renormalized.  Rate stats are recomputed from the blended counting
+// stats they're made of, rather than averaging the rates.
+//
+// Each source picks its own PlayerIDs, so sources which list their players
+// are joined on who the players are (see matchBlendedPlayer) rather than on
+// their ids.  Sources which don't are looked up by the blended id.
+type BlendedStatsClient struct {
+	sources []BlendSource
+	players []*blendedPlayer
+	byID    map[PlayerID]*blendedPlayer
+	// Which sources list their players.
+	listed []bool
+}
+
+// One player, as each source knows them.
+type blendedPlayer struct {
+	record PlayerRecord
+	ids    []PlayerID // By source; "" if the source doesn't have them
+}
+
+func NewBlendedStatsClient(sources ...BlendSource) *BlendedStatsClient {
+	bc := &BlendedStatsClient{
+		sources: sources,
+		byID:    make(map[PlayerID]*blendedPlayer),
+		listed:  make([]bool, len(sources)),
+	}
+
+	index := make(map[string]*blendedPlayer)
+	for i, source := range sources {
+		directory, ok := source.Client.(PlayerDirectory)
+		if !ok {
+			continue
+		}
+		records := directory.Players()
+		bc.listed[i] = len(records) > 0
+
+		names := make(map[string]int)
+		for _, record := range records {
+			names[nameAndSide(record)]++
+		}
+		for _, record := range records {
+			player := matchBlendedPlayer(index, record, i, names[nameAndSide(record)] == 1)
+			if player == nil {
+				player = &blendedPlayer{record: record, ids: make([]PlayerID, len(sources))}
+				for bc.byID[player.record.ID] != nil {
+					player.record.ID = PlayerID(fmt.Sprintf("%s [%s]", player.record.ID, source.Name))
+				}
+				bc.players = append(bc.players, player)
+				bc.byID[player.record.ID] = player
+			} else {
+				player.merge(record)
+			}
+			player.ids[i] = record.ID
+			indexBlendedPlayer(index, player)
+		}
+	}
+	return bc
+}
+
+// The keys a player can be joined on, most certain first.  Names are only
+// trusted along with the team, or when nobody else has the name.
+func blendedPlayerKeys(record PlayerRecord, uniqueName bool) []string {
+	keys := []string{}
+	if record.MLBAMID != "" {
+		keys = append(keys, "mlbam:"+record.MLBAMID)
+	}
+	if record.FanGraphsID != "" {
+		keys = append(keys, "fg:"+record.FanGraphsID)
+	}
+	if team := normalizeTeam(record.Team); team != "" {
+		keys = append(keys, "team:"+nameAndSide(record)+"|"+team)
+	}
+	if uniqueName {
+		keys = append(keys, "name:"+nameAndSide(record))
+	}
+	return keys
+}
+
+func nameAndSide(record PlayerRecord) string {
+	return normalizeName(record.Name) + "|" + record.Side
+}
+
+// Finds the player a source's record is, if an earlier source had them.
+func matchBlendedPlayer(index map[string]*blendedPlayer, record PlayerRecord, source int, uniqueName bool) *blendedPlayer {
+	for _, key := range blendedPlayerKeys(record, uniqueName) {
+		player, ok := index[key]
+		if !ok || player == nil || player.ids[source] != "" {
+			continue
+		}
+		// Different ids are different players, whatever their names.
+		if differ(player.record.MLBAMID, record.MLBAMID) || differ(player.record.FanGraphsID, record.FanGraphsID) {
+			continue
+		}
+		return player
+	}
+	return nil
+}
+
+func differ(a, b string) bool {
+	return a != "" && b != "" && a != b
+}
+
+func indexBlendedPlayer(index map[string]*blendedPlayer, player *blendedPlayer) {
+	keys := blendedPlayerKeys(player.record, true)
+	for _, key := range keys {
+		if other, ok := index[key]; ok && other != player && strings.HasPrefix(key, "name:") {
+			// Two players with the same name: it's no use for joining.
+			index[key] = nil
+			continue
+		}
+		if _, ok := index[key]; !ok {
+			index[key] = player
+		}
+	}
+}
+
+// Fills in whatever the first source to list a player didn't know.
+func (p *blendedPlayer) merge(record PlayerRecord) {
+	if p.record.Team == "" {
+		p.record.Team = record.Team
+	}
+	if p.record.FanGraphsID == "" {
+		p.record.FanGraphsID = record.FanGraphsID
+	}
+	if p.record.MLBAMID == "" {
+		p.record.MLBAMID = record.MLBAMID
+	}
+}
+
+// Every player known to any of the sources, once each, with the record of
+// the first source to list them.
+func (bc *BlendedStatsClient) Players() []PlayerRecord {
+	players := make([]PlayerRecord, len(bc.players))
+	for i, player := range bc.players {
+		players[i] = player.record
+	}
+	return players
+}
+
+func (bc *BlendedStatsClient) GetStat(player PlayerID, stat StatID) Stat {
+	return bc.GetStatLine(player)[stat]
+}
+
+func (bc *BlendedStatsClient) GetStatLine(player PlayerID) StatLine {
+	statline, _ := bc.GetBlendedStatLine(player)
+	return statline
+}
+
+// Same as GetStatLine, but also returns the names of the sources which had
+// the player.
+func (bc *BlendedStatsClient) GetBlendedStatLine(player PlayerID) (StatLine, []string) {
+	joined, known := bc.byID[player]
+	lines := []StatLine{}
+	sources := []BlendSource{}
+	for i, source := range bc.sources {
+		id := player
+		if known && bc.listed[i] {
+			id = joined.ids[i]
+			if id == "" {
+				continue
+			}
+		}
+		line := source.Client.GetStatLine(id)
+		if len(line) > 0 {
+			lines = append(lines, line)
+			sources = append(sources, source)
+		}
+	}
+
+	names := make([]string, len(sources))
+	for i, source := range sources {
+		names[i] = source.Name
+	}
+
+	return blend(lines, sources), names
+}
+
+func blend(lines []StatLine, sources []BlendSource) StatLine {
+	blended := make(StatLine)
+	rates := make(map[StatID]bool)
+
+	totals := make(map[StatID]float64)
+	weights := make(map[StatID]float64)
+	for i, line := range lines {
+		for stat, value := range line {
+			if isRateStat(stat) {
+				rates[stat] = true
+				continue
+			}
+			w := sources[i].weight(stat)
+			totals[stat] += w * float64(value)
+			weights[stat] += w
+		}
+	}
+	for stat, total := range totals {
+		if weights[stat] > 0 {
+			blended[stat] = Stat(total / weights[stat])
+		}
+	}
+
+	for stat := range rates {
+		if rate, ok := rateFromComponents(stat, blended); ok {
+			blended[stat] = rate
+		} else {
+			blended[stat] = blendRate(stat, lines, sources)
+		}
+	}
+
+	return blended
+}
+
+func rateFromComponents(stat StatID, line StatLine) (Stat, bool) {
+	formula := rateFormulaFor(stat)
+	if formula == nil {
+		return 0, false
+	}
+	n, hasNumerator := formula.numeratorOf(line)
+	d, hasDenominator := formula.denominatorOf(line)
+	if !hasNumerator || !hasDenominator || d <= 0 {
+		return 0, false
+	}
+	return formula.scale * n / d, true
+}
+
+// When the sources don't have the components of a rate stat, fall back to
+// averaging the rates themselves, weighted by both the source weight and
+// (where we have it) the playing time behind each rate.
+func blendRate(stat StatID, lines []StatLine, sources []BlendSource) Stat {
+	formula := rateFormulaFor(stat)
+
+	total, weight := float64(0), float64(0)
+	for i, line := range lines {
+		rate, ok := line[stat]
+		if !ok {
+			continue
+		}
+		w := sources[i].weight(stat)
+		if formula != nil {
+			if d, ok := formula.denominatorOf(line); ok {
+				w *= float64(d)
+			}
+		}
+		total += w * float64(rate)
+		weight += w
+	}
+	if weight <= 0 {
+		return 0
+	}
+	return Stat(total / weight)
+}
+
+// A StatsClient with another's counting stats scaled by a factor, e.g. to put
+// a season's stats so far on a full season pace, so they can be blended with
+// full season projections.  Rate stats are left as they are.
+type ScaledStatsClient struct {
+	client StatsClient
+	factor float64
+}
+
+func NewScaledStatsClient(client StatsClient, factor float64) *ScaledStatsClient {
+	return &ScaledStatsClient{client: client, factor: factor}
+}
+
+func (sc *ScaledStatsClient) Players() []PlayerRecord {
+	if directory, ok := sc.client.(PlayerDirectory); ok {
+		return directory.Players()
+	}
+	return nil
+}
+
+func (sc *ScaledStatsClient) GetStat(player PlayerID, stat StatID) Stat {
+	return sc.GetStatLine(player)[stat]
+}
+
+func (sc *ScaledStatsClient) GetStatLine(player PlayerID) StatLine {
+	line := sc.client.GetStatLine(player)
+	if len(line) == 0 {
+		return line
+	}
+	return scaleStatLine(line, sc.factor)
+}
@@ -0,0 +1,214 @@
+package folib
+
+import (
+	"reflect"
+	"testing"
+)
+
+type fakeStatsClient map[PlayerID]StatLine
+
+func (fc fakeStatsClient) GetStat(player PlayerID, stat StatID) Stat {
+	return fc[player][stat]
+}
+
+func (fc fakeStatsClient) GetStatLine(player PlayerID) StatLine {
+	return fc[player]
+}
+
+// An FO with the given projections and settings, which knows players by
+// name.  Tests fill in the rest (e.g. yahoo, league) as they need it.
+func testFO(projections StatsClient, settings *LeagueSettings) *FO {
+	return &FO{
+		projections: projections,
+		settings:    settings,
+		players:     NewPlayerResolver([]PlayerRecord{}, []PlayerOverride{}),
+	}
+}
+
+func TestBlendWeightsSources(t *testing.T) {
+	a := fakeStatsClient{"Slugger": StatLine{B_HOME_RUNS: 40}}
+	b := fakeStatsClient{"Slugger": StatLine{B_HOME_RUNS: 20}}
+
+	client := NewBlendedStatsClient(
+		BlendSource{Name: "a", Client: a, Weight: 3},
+		BlendSource{Name: "b", Client: b, Weight: 1})
+
+	line, sources := client.GetBlendedStatLine("Slugger")
+	if line[B_HOME_RUNS] != 35 {
+		t.Errorf("Expected 35 HR, got: %f", line[B_HOME_RUNS])
+	}
+	if !reflect.DeepEqual(sources, []string{"a", "b"}) {
+		t.Errorf("Expected both sources, got: %v", sources)
+	}
+}
+
+func TestBlendPerStatWeights(t *testing.T) {
+	a := fakeStatsClient{"Ace": StatLine{P_STRIKE_OUTS: 200, P_WINS: 10}}
+	b := fakeStatsClient{"Ace": StatLine{P_STRIKE_OUTS: 100, P_WINS: 20}}
+
+	client := NewBlendedStatsClient(
+		BlendSource{Name: "a", Client: a, Weight: 1, StatWeights: map[StatID]float64{P_WINS: 0}},
+		BlendSource{Name: "b", Client: b, Weight: 1})
+
+	line := client.GetStatLine("Ace")
+	if line[P_STRIKE_OUTS] != 150 {
+		t.Errorf("Expected 150 K, got: %f", line[P_STRIKE_OUTS])
+	}
+	if line[P_WINS] != 20 {
+		t.Errorf("Expected wins to come only from b, got: %f", line[P_WINS])
+	}
+}
+
+func TestBlendMissingPlayersAndStats(t *testing.T) {
+	a := fakeStatsClient{
+		"Everywhere": StatLine{P_SAVES: 40, P_STRIKE_OUTS: 80},
+		"Rookie":     StatLine{B_HOME_RUNS: 10},
+	}
+	b := fakeStatsClient{"Everywhere": StatLine{P_STRIKE_OUTS: 60}}
+
+	client := NewBlendedStatsClient(
+		BlendSource{Name: "a", Client: a, Weight: 1},
+		BlendSource{Name: "b", Client: b, Weight: 1})
+
+	line, sources := client.GetBlendedStatLine("Rookie")
+	if line[B_HOME_RUNS] != 10 {
+		t.Errorf("Missing sources shouldn't drag down the blend, got: %f", line[B_HOME_RUNS])
+	}
+	if !reflect.DeepEqual(sources, []string{"a"}) {
+		t.Errorf("Expected only source a, got: %v", sources)
+	}
+
+	line = client.GetStatLine("Everywhere")
+	if line[P_SAVES] != 40 || line[P_STRIKE_OUTS] != 70 {
+		t.Errorf("Expected 40 SV and 70 K, got: %v", line)
+	}
+
+	line, sources = client.GetBlendedStatLine("Nobody")
+	if len(line) != 0 || len(sources) != 0 {
+		t.Errorf("Expected nothing for an unknown player, got %v from %v", line, sources)
+	}
+}
+
+func TestBlendRecomputesRateStats(t *testing.T) {
+	a := fakeStatsClient{"Ace": StatLine{P_EARNED_RUNS: 40, P_INNINGS: 200, P_EARNED_RUN_AVERAGE: 1.80}}
+	b := fakeStatsClient{"Ace": StatLine{P_EARNED_RUNS: 90, P_INNINGS: 100, P_EARNED_RUN_AVERAGE: 8.10}}
+
+	client := NewBlendedStatsClient(
+		BlendSource{Name: "a", Client: a, Weight: 1},
+		BlendSource{Name: "b", Client: b, Weight: 1})
+
+	// 65 ER in 150 IP, rather than the average of the ERAs (4.95).
+	line := client.GetStatLine("Ace")
+	if !closeEnough(line[P_EARNED_RUN_AVERAGE], 3.90) {
+		t.Errorf("Expected ERA of 3.90, got: %f", line[P_EARNED_RUN_AVERAGE])
+	}
+}
+
+func TestBlendRateStatsWithoutComponents(t *testing.T) {
+	a := fakeStatsClient{"Closer": StatLine{P_WHIP: 1.00, P_INNINGS: 75}}
+	b := fakeStatsClient{"Closer": StatLine{P_WHIP: 1.40, P_INNINGS: 25}}
+
+	client := NewBlendedStatsClient(
+		BlendSource{Name: "a", Client: a, Weight: 1},
+		BlendSource{Name: "b", Client: b, Weight: 1})
+
+	line := client.GetStatLine("Closer")
+	if !closeEnough(line[P_WHIP], 1.10) {
+		t.Errorf("Expected WHIP of 1.10, got: %f", line[P_WHIP])
+	}
+}
+
+func TestBlendSeasonSoFarWithProjections(t *testing.T) {
+	actuals := loadFanGraphsFixtures(t)
+	// Trout has played the most games, 157.
+	if fraction := actuals.SeasonFraction(); !closeEnough(Stat(fraction), 157.0/162) {
+		t.Errorf("Expected 157/162 of the season played, got %f", fraction)
+	}
+
+	halfway := NewScaledStatsClient(fakeStatsClient{
+		"Mike Trout": {B_HOME_RUNS: 15, B_HITS: 90, B_AT_BATS: 300, B_BATTING_AVG: .300},
+	}, 2)
+	projections := fakeStatsClient{
+		"Mike Trout": {B_HOME_RUNS: 40, B_HITS: 170, B_AT_BATS: 600, B_BATTING_AVG: .283},
+	}
+	line, _ := NewBlendedStatsClient(
+		BlendSource{Name: "zips", Client: projections, Weight: 1},
+		BlendSource{Name: "fg", Client: halfway, Weight: 1},
+	).GetBlendedStatLine("Mike Trout")
+
+	// A full season's 30 HR pace, not the 15 so far, averaged with 40.
+	if line[B_HOME_RUNS] != 35 || line[B_AT_BATS] != 600 {
+		t.Errorf("Expected the season so far on a full season pace, got %v", line)
+	}
+	if !closeEnough(line[B_BATTING_AVG], .2917) {
+		t.Errorf("Expected AVG of .2917, got %f", line[B_BATTING_AVG])
+	}
+
+	if players := NewScaledStatsClient(actuals, 2).Players(); len(players) != len(actuals.Players()) {
+		t.Errorf("Expected the scaled client to list the same players, got %d", len(players))
+	}
+	if line := NewScaledStatsClient(actuals, 2).GetStatLine("Nobody"); len(line) != 0 {
+		t.Errorf("Expected nothing for an unknown player, got %v", line)
+	}
+}
+
+// A fakeStatsClient which lists its players.
+type fakeDirectory struct {
+	fakeStatsClient
+	players []PlayerRecord
+}
+
+func (fd fakeDirectory) Players() []PlayerRecord {
+	return fd.players
+}
+
+func TestBlendJoinsSourcesOnWhoPlayersAre(t *testing.T) {
+	zips := fakeDirectory{
+		fakeStatsClient{
+			"Ronald Acuna Jr.":   {B_HOME_RUNS: 30},
+			"Chris Young [NYM]":  {B_HOME_RUNS: 10},
+			"Chris Young [NYY]":  {B_HOME_RUNS: 20},
+			"Only In Projection": {B_HOME_RUNS: 5},
+		},
+		[]PlayerRecord{
+			{ID: "Ronald Acuna Jr.", Name: "Ronald Acuna Jr.", Team: "ATL", Side: BATTING},
+			{ID: "Chris Young [NYM]", Name: "Chris Young", Team: "NYM", Side: BATTING},
+			{ID: "Chris Young [NYY]", Name: "Chris Young", Team: "NYY", Side: BATTING},
+			{ID: "Only In Projection", Name: "Only In Projection", Team: "BOS", Side: BATTING},
+		},
+	}
+	// FanGraphs spells Acuña differently, and only has one Chris Young, so
+	// gives him a different id.
+	fg := fakeDirectory{
+		fakeStatsClient{
+			"Ronald Acuña": {B_HOME_RUNS: 40},
+			"Chris Young":  {B_HOME_RUNS: 30},
+		},
+		[]PlayerRecord{
+			{ID: "Ronald Acuña", Name: "Ronald Acuña", Team: "Braves", Side: BATTING, FanGraphsID: "18401"},
+			{ID: "Chris Young", Name: "Chris Young", Team: "Yankees", Side: BATTING, FanGraphsID: "3882"},
+		},
+	}
+	client := NewBlendedStatsClient(
+		BlendSource{Name: "zips", Client: zips, Weight: 1},
+		BlendSource{Name: "fg", Client: fg, Weight: 1})
+
+	players := client.Players()
+	if len(players) != 4 {
+		t.Errorf("Expected each player once, got: %v", players)
+	}
+	if players[0].ID != "Ronald Acuna Jr." || players[0].FanGraphsID != "18401" {
+		t.Errorf("Expected FanGraphs' id to be filled in, got: %v", players[0])
+	}
+
+	line, sources := client.GetBlendedStatLine("Ronald Acuna Jr.")
+	if line[B_HOME_RUNS] != 35 || len(sources) != 2 {
+		t.Errorf("Expected both sources for Acuña, got %v from %v", line, sources)
+	}
+	if line := client.GetStatLine("Chris Young [NYY]"); line[B_HOME_RUNS] != 25 {
+		t.Errorf("Expected both sources for the Yankee, got %v", line)
+	}
+	if line := client.GetStatLine("Chris Young [NYM]"); line[B_HOME_RUNS] != 10 {
+		t.Errorf("Expected only ZiPS for the Met, got %v", line)
+	}
+}
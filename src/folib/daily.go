@@ -0,0 +1,300 @@
+package folib
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Whether a player is expected to play on a given day.
+type DailyStatus int
+
+const (
+	// In the posted lineup, or a probable starting pitcher.
+	PLAYING DailyStatus = iota
+	// We don't know yet, e.g. because lineups haven't been posted.
+	MAYBE_PLAYING
+	// The player's team has no game that day (which we only know with the
+	// schedule).  They're only started if nobody else can fill the slot.
+	OFF_DAY
+	// Left out of the posted lineup, or a starting pitcher who isn't
+	// scheduled to start.
+	NOT_PLAYING
+)
+
+// One day of a weekly lineup plan.
+type DailyLineup struct {
+	Date     string
+	Starters map[Position][]YahooPlayer
+	Changes  []LineupChange
+}
+
+// My lineups for the rest of a scoring week.
+type WeekPlan struct {
+	Days []DailyLineup
+	// How many more games each player (by player key) might play this week,
+	// as of the first day of the plan.
+	GamesRemaining map[string]int
+	// How many of those games each player starts.
+	Starts map[string]int
+	// The projected stats of my starters over the rest of the week.
+	Projection StatLine
+}
+
+// Works out whether each player on a roster (fetched for date) will play
+// that day, from Yahoo's starting_status.  Once any player has a status for
+// the date, lineups and probable pitchers are out, so a starting pitcher
+// without one isn't starting.  Before that, nobody's status is known.
+func dailyStatuses(roster []YahooPlayer, date string) []DailyStatus {
+	posted := false
+	for _, player := range roster {
+		if _, ok := startingStatusOn(player, date); ok {
+			posted = true
+		}
+	}
+
+	statuses := make([]DailyStatus, len(roster))
+	for i, player := range roster {
+		starting, ok := startingStatusOn(player, date)
+		switch {
+		case ok && starting:
+			statuses[i] = PLAYING
+		case ok:
+			statuses[i] = NOT_PLAYING
+		case posted && isStartingPitcher(player):
+			statuses[i] = NOT_PLAYING
+		default:
+			statuses[i] = MAYBE_PLAYING
+		}
+	}
+	return statuses
+}
+
+func startingStatusOn(player YahooPlayer, date string) (starting bool, ok bool) {
+	for _, status := range player.StartingStatus {
+		if status.Date == date {
+			return status.IsStarting == 1, true
+		}
+	}
+	return false, false
+}
+
+// Pitchers who only start, and so only help on days they're scheduled to.
+func isStartingPitcher(player YahooPlayer) bool {
+	if player.PositionType != "P" {
+		return false
+	}
+	starter := false
+	for _, pos := range player.Position {
+		switch pos {
+		case "SP":
+			starter = true
+		case "RP":
+			return false
+		}
+	}
+	return starter
+}
+
+// Fills in what the schedule (if we have one) tells us about players whose
+// status is otherwise unknown: nobody plays on their team's off days, and
+// starting pitchers only play when they're the probable starter.
+func (fo *FO) scheduledStatuses(roster []YahooPlayer, date string, statuses []DailyStatus) []DailyStatus {
+	day, err := time.Parse("2006-01-02", date)
+	if fo.schedule == nil || err != nil {
+		return statuses
+	}
+
+	for i, player := range roster {
+		team := normalizeTeam(player.TeamAbbr)
+		if statuses[i] != MAYBE_PLAYING || team == "" {
+			continue
+		}
+		switch {
+		case fo.schedule.Games(team, day, day) == 0:
+			statuses[i] = OFF_DAY
+		case !isStartingPitcher(player):
+		case fo.schedule.ExpectedStarts(player.FullName, team, day, day) >= 1:
+			statuses[i] = PLAYING
+		case fo.schedule.ExpectedStarts(player.FullName, team, day, day) == 0:
+			statuses[i] = NOT_PLAYING
+		}
+	}
+	return statuses
+}
+
+// When choosing who to start today, how much more a player worth as much as
+// another, but with no other games left this week, is worth than one who
+// plays every remaining day.
+const LAST_CHANCE_BONUS = 0.05
+
+// What each player's status is on date, from Yahoo and the schedule.
+func (fo *FO) statusesOn(roster []YahooPlayer, date string) []DailyStatus {
+	return fo.scheduledStatuses(roster, date, dailyStatuses(roster, date))
+}
+
+// Like selectStarters, but for one day: players who aren't playing are
+// benched, anyone confirmed to be playing starts ahead of anyone who might
+// not be, and players whose team is off only start if nobody else can.
+//
+// gamesRemaining (by player key, and optional) is how many games each player
+// might play from today through the end of the week.  Between players worth
+// about the same, the one with fewer games left gets the start, since today
+// is more of their week.
+func (fo *FO) selectDailyStarters(roster []YahooPlayer, date string, topology RosterTopology, gamesRemaining map[string]int) map[Position][]YahooPlayer {
+	values := fo.starterValues(roster)
+	statuses := fo.statusesOn(roster, date)
+
+	mostGames := 0
+	for _, player := range roster {
+		mostGames = max(mostGames, gamesRemaining[player.PlayerKey])
+	}
+	for i, player := range roster {
+		games, ok := gamesRemaining[player.PlayerKey]
+		if ok && mostGames > 0 && values[i] > 0 {
+			values[i] *= 1 + LAST_CHANCE_BONUS*float32(mostGames-games)/float32(mostGames)
+		}
+	}
+
+	// Bump each tier's values by more than the spread of all values, so
+	// players who are surer to play always win out.
+	lowest, highest := float32(0), float32(0)
+	for _, v := range values {
+		lowest = min(lowest, v)
+		highest = max(highest, v)
+	}
+	bump := highest - lowest + 1
+	tiers := map[DailyStatus]float32{PLAYING: 2, MAYBE_PLAYING: 1, OFF_DAY: 0}
+
+	available := []YahooPlayer{}
+	availableValues := []float32{}
+	for i, player := range roster {
+		if statuses[i] == NOT_PLAYING {
+			continue
+		}
+		available = append(available, player)
+		availableValues = append(availableValues, values[i]+tiers[statuses[i]]*bump)
+	}
+
+	starters := make(map[Position][]YahooPlayer)
+	for pos, indices := range optimalLineup(available, availableValues, topology) {
+		for _, i := range indices {
+			starters[pos] = append(starters[pos], available[i])
+		}
+	}
+	return starters
+}
+
+// Plans (and, unless dryRun, sets) my daily lineups from date through the
+// end of its scoring week.
+func (fo *FO) OptimizeWeek(ctx context.Context, date string, dryRun bool) (*WeekPlan, error) {
+	err := fo.loadSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	start, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return nil, err
+	}
+	end, err := fo.weekEnd(ctx, start)
+	if err != nil {
+		return nil, err
+	}
+
+	dates := []string{}
+	rosters := [][]YahooPlayer{}
+	for day := start; !day.After(end); day = day.Add(ONE_DAY) {
+		date := day.Format("2006-01-02")
+		roster, err := fo.yahoo.GetLineup(ctx, fo.league.MyTeamKey, date)
+		if err != nil {
+			return nil, err
+		}
+		dates = append(dates, date)
+		rosters = append(rosters, roster)
+	}
+
+	// Count, for each day, the games each player might play from then on.
+	remaining := make([]map[string]int, len(dates))
+	for i := len(dates) - 1; i >= 0; i-- {
+		remaining[i] = make(map[string]int)
+		for j, status := range fo.statusesOn(rosters[i], dates[i]) {
+			key := rosters[i][j].PlayerKey
+			if i+1 < len(dates) {
+				remaining[i][key] = remaining[i+1][key]
+			}
+			if status == PLAYING || status == MAYBE_PLAYING {
+				remaining[i][key]++
+			}
+		}
+	}
+
+	plan := &WeekPlan{Starts: make(map[string]int)}
+	if len(remaining) > 0 {
+		plan.GamesRemaining = remaining[0]
+	}
+	lines := []StatLine{}
+	for i, date := range dates {
+		day, _ := time.Parse("2006-01-02", date)
+		starters := fo.selectDailyStarters(rosters[i], date, fo.settings.Topology, remaining[i])
+		plan.Days = append(plan.Days, DailyLineup{
+			Date:     date,
+			Starters: starters,
+			Changes:  lineupChanges(rosters[i], starters),
+		})
+
+		for _, players := range starters {
+			for _, player := range players {
+				plan.Starts[player.PlayerKey]++
+				line := fo.projections.GetStatLine(fo.playerID(player))
+				lines = append(lines, scaleStatLine(line, fo.projectionShare(player, line, day, day)))
+			}
+		}
+	}
+	plan.Projection = merge(lines)
+
+	if dryRun {
+		return plan, nil
+	}
+	// Yahoo carries changes forward to the rest of the week, so once we've
+	// changed one day, the lineups we fetched for the days after it are out
+	// of date.  From then on, we send each day's whole lineup.
+	changed := false
+	for i, day := range plan.Days {
+		if len(day.Changes) == 0 && !changed {
+			continue
+		}
+		err = fo.yahoo.SetLineup(ctx, fo.league.MyTeamKey, day.Date, lineupAssignments(rosters[i], day.Starters))
+		if err != nil {
+			return nil, fmt.Errorf("Setting lineup for %s: %s", day.Date, err)
+		}
+		changed = true
+	}
+	return plan, nil
+}
+
+// The last day of the scoring week containing day: the end of my current
+// matchup if I have one (and it covers day), and otherwise the following
+// Sunday, but never past the end of the season.
+func (fo *FO) weekEnd(ctx context.Context, day time.Time) (time.Time, error) {
+	end := day.Add(time.Duration((7-int(day.Weekday()))%7) * ONE_DAY)
+
+	scoreboard, err := fo.yahoo.GetScoreboard(ctx, fo.league.LeagueKey, 0)
+	if err != nil {
+		return end, err
+	}
+	if matchup, _, _, err := findMatchup(scoreboard, fo.league.MyTeamKey); err == nil {
+		matchupEnd, err := time.Parse("2006-01-02", matchup.WeekEnd)
+		if err != nil {
+			return end, err
+		}
+		if !matchupEnd.Before(day) {
+			end = matchupEnd
+		}
+	}
+
+	if !fo.settings.Season.IsZero() && end.After(fo.settings.Season.End) {
+		end = fo.settings.Season.End
+	}
+	return end, nil
+}
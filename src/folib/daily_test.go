@@ -0,0 +1,235 @@
+package folib
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func startingOn(player YahooPlayer, date string, starting bool) YahooPlayer {
+	status := YahooStartingStatus{Date: date}
+	if starting {
+		status.IsStarting = 1
+	}
+	player.StartingStatus = append(player.StartingStatus, status)
+	return player
+}
+
+func TestDailyStatuses(t *testing.T) {
+	roster := []YahooPlayer{
+		startingOn(batter("In Lineup", "OF"), "2014-05-01", true),
+		startingOn(batter("Sitting", "OF"), "2014-05-01", false),
+		// Lineups are posted, but not this player's team's yet.
+		batter("Not Posted", "OF"),
+		pitcher("Not Starting", "SP"),
+		pitcher("Reliever", "RP"),
+		startingOn(pitcher("Probable", "SP"), "2014-05-01", true),
+	}
+
+	statuses := dailyStatuses(roster, "2014-05-01")
+	expected := []DailyStatus{PLAYING, NOT_PLAYING, MAYBE_PLAYING, NOT_PLAYING, MAYBE_PLAYING, PLAYING}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Errorf("Expected %v, got %v", expected, statuses)
+	}
+
+	// Nobody knows anything about tomorrow yet.
+	statuses = dailyStatuses(roster, "2014-05-02")
+	for i, status := range statuses {
+		if status != MAYBE_PLAYING {
+			t.Errorf("Expected %s to be unknown tomorrow, got %v", roster[i].FullName, status)
+		}
+	}
+}
+
+func TestSelectDailyStarters(t *testing.T) {
+	fo := testFO(fakeStatsClient{
+		"Star":    {B_HOME_RUNS: 40},
+		"Slugger": {B_HOME_RUNS: 30},
+		"Scrub":   {B_HOME_RUNS: 5},
+		"Ace":     {P_STRIKE_OUTS: 250},
+		"Fifth":   {P_STRIKE_OUTS: 100},
+	}, &LeagueSettings{
+		Categories: ScoringCategories{B_HOME_RUNS: HIGHER_IS_BETTER, P_STRIKE_OUTS: HIGHER_IS_BETTER},
+		Topology:   RosterTopology{Starters: map[Position]int{"OF": 1, "Util": 1, "SP": 1}},
+	})
+
+	date := "2014-05-01"
+	roster := []YahooPlayer{
+		startingOn(batter("Star", "OF"), date, false),
+		batter("Slugger", "OF"),
+		startingOn(batter("Scrub", "OF"), date, true),
+		pitcher("Ace", "SP"),
+		startingOn(pitcher("Fifth", "SP"), date, true),
+	}
+
+	starters := make(map[string]bool)
+	for _, players := range fo.selectDailyStarters(roster, date, fo.settings.Topology, nil) {
+		for _, player := range players {
+			starters[player.FullName] = true
+		}
+	}
+
+	// Star sits, and only Fifth is pitching today.
+	expected := map[string]bool{"Scrub": true, "Slugger": true, "Fifth": true}
+	if !reflect.DeepEqual(starters, expected) {
+		t.Errorf("Expected %v, got %v", expected, starters)
+	}
+}
+
+func TestSelectDailyStartersOffDaysAndGamesRemaining(t *testing.T) {
+	fo := testFO(fakeStatsClient{
+		"Star":      {B_HOME_RUNS: 40},
+		"Regular":   {B_HOME_RUNS: 20},
+		"Part Time": {B_HOME_RUNS: 19.5},
+	}, &LeagueSettings{
+		// Points, so that values aren't just ranks.
+		PointWeights: PointWeights{B_HOME_RUNS: 1},
+		Topology:     RosterTopology{Starters: map[Position]int{"OF": 1}},
+	})
+	fo.schedule = NewSchedule([]ScheduledGame{
+		{Date: "2014-05-01", Away: "NYY", Home: "BOS"},
+	})
+	star := YahooPlayer{PlayerKey: "1", FullName: "Star", PositionType: "B", Position: []string{"OF"}, TeamAbbr: "DET"}
+	regular := YahooPlayer{PlayerKey: "2", FullName: "Regular", PositionType: "B", Position: []string{"OF"}, TeamAbbr: "NYY"}
+	partTime := YahooPlayer{PlayerKey: "3", FullName: "Part Time", PositionType: "B", Position: []string{"OF"}, TeamAbbr: "BOS"}
+
+	starter := func(roster []YahooPlayer, gamesRemaining map[string]int) string {
+		starters := fo.selectDailyStarters(roster, "2014-05-01", fo.settings.Topology, gamesRemaining)
+		if len(starters["OF"]) != 1 {
+			t.Fatalf("Expected one starter, got %v", starters)
+		}
+		return starters["OF"][0].FullName
+	}
+
+	// Detroit is off, so the Star only starts if there's nobody else.
+	if name := starter([]YahooPlayer{star, regular}, nil); name != "Regular" {
+		t.Errorf("Expected the player whose team is playing to start, got %s", name)
+	}
+	if name := starter([]YahooPlayer{star}, nil); name != "Star" {
+		t.Errorf("Expected the Star to fill an otherwise empty slot, got %s", name)
+	}
+
+	// Between players worth about the same, start the one with fewer games
+	// left this week.
+	if name := starter([]YahooPlayer{regular, partTime}, nil); name != "Regular" {
+		t.Errorf("Expected the better player without games remaining, got %s", name)
+	}
+	if name := starter([]YahooPlayer{regular, partTime}, map[string]int{"2": 6, "3": 2}); name != "Part Time" {
+		t.Errorf("Expected the player with fewer games left, got %s", name)
+	}
+}
+
+const weekScoreboardXml = `<?xml version="1.0" encoding="UTF-8"?>
+<fantasy_content>
+  <league>
+    <scoreboard>
+      <week>5</week>
+      <matchups>
+        <matchup>
+          <week>5</week>
+          <week_start>2014-04-28</week_start>
+          <week_end>2014-05-04</week_end>
+          <teams>
+            <team><team_key>328.l.1.t.1</team_key></team>
+            <team><team_key>328.l.1.t.2</team_key></team>
+          </teams>
+        </matchup>
+      </matchups>
+    </scoreboard>
+  </league>
+</fantasy_content>`
+
+func weekRosterXml(date string, starting int) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<fantasy_content>
+  <team>
+    <roster>
+      <players>
+        <player>
+          <player_key>328.p.1</player_key>
+          <name><full>Slugger</full></name>
+          <position_type>B</position_type>
+          <eligible_positions><position>OF</position></eligible_positions>
+          <selected_position><position>OF</position></selected_position>
+          <starting_status><date>%s</date><is_starting>%d</is_starting></starting_status>
+        </player>
+        <player>
+          <player_key>328.p.2</player_key>
+          <name><full>Scrub</full></name>
+          <position_type>B</position_type>
+          <eligible_positions><position>OF</position></eligible_positions>
+          <selected_position><position>BN</position></selected_position>
+        </player>
+      </players>
+    </roster>
+  </team>
+</fantasy_content>`, date, starting)
+}
+
+func TestOptimizeWeek(t *testing.T) {
+	yahoo, client := newFakeYahoo(t)
+	yahoo.respond("/league/328.l.1/scoreboard", weekScoreboardXml)
+	yahoo.respond("/team/328.l.1.t.1/roster;date=2014-05-02", weekRosterXml("2014-05-02", 1))
+	yahoo.respond("/team/328.l.1.t.1/roster;date=2014-05-03", weekRosterXml("2014-05-03", 0))
+	yahoo.respond("/team/328.l.1.t.1/roster;date=2014-05-04", weekRosterXml("2014-05-04", 1))
+
+	fo := testFO(fakeStatsClient{
+		"Slugger": {B_HOME_RUNS: 40},
+		"Scrub":   {B_HOME_RUNS: 1},
+	}, &LeagueSettings{
+		Categories: ScoringCategories{B_HOME_RUNS: HIGHER_IS_BETTER},
+		Topology:   RosterTopology{Starters: map[Position]int{"OF": 1}, Bench: 1},
+	})
+	fo.yahoo = client
+	fo.league = &LeagueContext{LeagueKey: "328.l.1", MyTeamKey: "328.l.1.t.1", MyTeamID: 1}
+
+	plan, err := fo.OptimizeWeek(context.Background(), "2014-05-02", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Days) != 3 {
+		t.Fatalf("Expected a plan through the end of the matchup, got %d days", len(plan.Days))
+	}
+	if plan.Starts["328.p.1"] != 2 || plan.Starts["328.p.2"] != 1 {
+		t.Errorf("Expected Slugger to start twice and Scrub once, got: %v", plan.Starts)
+	}
+	// Slugger is out on 2014-05-03, and nobody knows about Scrub.
+	if plan.GamesRemaining["328.p.1"] != 2 || plan.GamesRemaining["328.p.2"] != 3 {
+		t.Errorf("Expected Slugger to have 2 games left and Scrub 3, got: %v", plan.GamesRemaining)
+	}
+	expectedHomeRuns := float64(40*2+1) / SEASON_DAYS
+	if homeRuns := float64(plan.Projection[B_HOME_RUNS]); homeRuns < expectedHomeRuns-1e-6 || homeRuns > expectedHomeRuns+1e-6 {
+		t.Errorf("Expected %f home runs, got %f", expectedHomeRuns, homeRuns)
+	}
+
+	// Nothing changes until the day Slugger sits.  Benching him carries
+	// forward, so the next day has to put him back, even though the lineup we
+	// fetched for it already has him in place.
+	writes := yahoo.writes()
+	if len(writes) != 2 || writes[0].Path != "/team/328.l.1.t.1/roster" {
+		t.Fatalf("Expected lineups for the last two days, got: %v", writes)
+	}
+	expected := []map[string]Position{
+		{"328.p.1": BENCH, "328.p.2": "OF"},
+		{"328.p.1": "OF", "328.p.2": BENCH},
+	}
+	for i, write := range writes {
+		var request setLineupRequest
+		if err := xml.Unmarshal([]byte(write.Body), &request); err != nil {
+			t.Fatal(err)
+		}
+		assignments := make(map[string]Position)
+		for _, player := range request.Players {
+			assignments[player.PlayerKey] = player.Position
+		}
+		if request.Date != plan.Days[i+1].Date || !reflect.DeepEqual(assignments, expected[i]) {
+			t.Errorf("Expected %v on %s, got %v on %s", expected[i], plan.Days[i+1].Date, assignments, request.Date)
+		}
+	}
+	if changes := plan.Days[1].Changes; len(changes) != 2 || changes[0].To != BENCH || changes[1].To != "OF" {
+		t.Errorf("Expected to swap Slugger for Scrub on 2014-05-03, got: %v", changes)
+	}
+}
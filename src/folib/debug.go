@@ -2,6 +2,9 @@ package folib
 
 import (
 	"fmt"
+	"math"
+	"sort"
+	"strings"
 )
 
 func printRawScores(scores map[TeamID]map[StatID]int, stats map[TeamID]StatLine) {
@@ -28,25 +31,183 @@ func printRoster(starters map[Position][]YahooPlayer) {
 	}
 }
 
+var battingDisplayStats = []StatID{
+	B_BATTING_AVG, B_HOME_RUNS, B_RUNS, B_RUNS_BATTED_IN, B_STOLEN_BASES,
+}
+
+var pitchingDisplayStats = []StatID{
+	P_WINS, P_SAVES, P_STRIKE_OUTS, P_EARNED_RUN_AVERAGE, P_WHIP,
+}
+
 func FormatBattingStats(stats StatLine) string {
-	return fmt.Sprintf("AVG:%0.3f HR:%02d R:%03d RBI:%03d SB:%02d",
-		stats[B_BATTING_AVG],
-		int(stats[B_HOME_RUNS]),
-		int(stats[B_RUNS]),
-		int(stats[B_RUNS_BATTED_IN]),
-		int(stats[B_STOLEN_BASES]))
+	return FormatStats(stats, battingDisplayStats)
 }
 
 func FormatPitchingStats(stats StatLine) string {
-	whip, ok := stats[P_WHIP]
-	if !ok {
-		whip = (stats[P_WALKS] + stats[P_HITS]) / stats[P_INNINGS]
+	return FormatStats(stats, pitchingDisplayStats)
+}
+
+func PrintMatchupProjection(projection *MatchupProjection) {
+	fmt.Printf("Week %d vs. %s\n", projection.Week, projection.Opponent)
+	for _, c := range projection.Categories {
+		fmt.Printf("%-5s %s vs %s -> %0.0f%%\n",
+			StatName(c.Stat), FormatStat(c.Stat, c.Mine), FormatStat(c.Stat, c.Theirs), 100*c.WinProbability)
+	}
+	fmt.Printf("Expected record: %0.1f-%0.1f-%0.1f\n",
+		projection.ExpectedWins, projection.ExpectedLosses, projection.ExpectedTies)
+}
+
+func PrintSeasonProjection(projection *SeasonProjection) {
+	fmt.Printf("Projected final standings (%0.0f%% of the season played)\n", 100*projection.SeasonComplete)
+	for i, team := range projection.Standings() {
+		final := projection.Final[team]
+		fmt.Printf("%2d. TEAM %d: %.1f\n", i+1, team, projection.Scores[team])
+		fmt.Printf("    %s\n", FormatBattingStats(final))
+		fmt.Printf("    %s\n", FormatPitchingStats(final))
+	}
+}
+
+func PrintStandingsSimulation(simulation *StandingsSimulation) {
+	fmt.Printf("%d simulated seasons\n", simulation.Trials)
+	for _, team := range simulation.Standings() {
+		outlook := simulation.Teams[team]
+
+		stats := make([]StatID, 0, len(outlook.CategoryRankProbability))
+		for stat := range outlook.CategoryRankProbability {
+			stats = append(stats, stat)
+		}
+		sort.Slice(stats, func(i, j int) bool { return stats[i] < stats[j] })
+
+		fmt.Printf("TEAM %d: %.1f points (%.1f-%.1f), %0.1f%% to win\n",
+			team, outlook.MeanPoints, outlook.Percentile(0.1), outlook.Percentile(0.9),
+			100*outlook.FinishProbability[0])
+
+		places := make([]string, len(outlook.FinishProbability))
+		for i, p := range outlook.FinishProbability {
+			places[i] = fmt.Sprintf("%3.0f%%", 100*p)
+		}
+		fmt.Printf("    Finish:  %s\n", strings.Join(places, " "))
+
+		for _, stat := range stats {
+			ranks := make([]string, len(outlook.CategoryRankProbability[stat]))
+			for i, p := range outlook.CategoryRankProbability[stat] {
+				ranks[i] = fmt.Sprintf("%3.0f%%", 100*p)
+			}
+			fmt.Printf("    %-7s  %s\n", StatName(stat)+":", strings.Join(ranks, " "))
+		}
+	}
+}
+
+func PrintTradeEvaluation(evaluation *TradeEvaluation) {
+	fmt.Println(evaluation.Proposal)
+
+	for _, team := range []TeamID{evaluation.Proposal.A.Team, evaluation.Proposal.B.Team} {
+		before, after := evaluation.Before[team], evaluation.After[team]
+		fmt.Printf("TEAM %d: %s -> %s\n", team, FormatBattingStats(before), FormatBattingStats(after))
+		fmt.Printf("TEAM %d: %s -> %s\n", team, FormatPitchingStats(before), FormatPitchingStats(after))
+	}
+
+	teams := make([]TeamID, 0, len(evaluation.Deltas))
+	for team := range evaluation.Deltas {
+		teams = append(teams, team)
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i] < teams[j] })
+
+	fmt.Printf("Delta\n")
+	for _, team := range teams {
+		fmt.Printf("TEAM %d: %.1f -> %.1f (%+.1f)\n",
+			team, evaluation.BeforeScores[team], evaluation.AfterScores[team], evaluation.Deltas[team])
+	}
+}
+
+func PrintTrades(trades []*TradeEvaluation) {
+	for _, trade := range trades {
+		a, b := trade.Proposal.A.Team, trade.Proposal.B.Team
+		fmt.Printf("%+.1f / %+.1f: %s\n", trade.Deltas[a], trade.Deltas[b], trade.Proposal)
+	}
+}
+
+func PrintPickups(recommendations map[string][]PickupRecommendation) {
+	positions := make([]string, 0, len(recommendations))
+	for position := range recommendations {
+		positions = append(positions, position)
+	}
+	sort.Strings(positions)
+
+	for _, position := range positions {
+		fmt.Printf("%s\n", position)
+		for _, r := range recommendations[position] {
+			drop := r.Drop.FullName
+			if drop == "" {
+				drop = "nobody"
+			}
+			fmt.Printf("  %+.1f: add %s, drop %s\n", r.Delta, r.Add.FullName, drop)
+		}
+	}
+}
+
+func PrintLineupChanges(changes []LineupChange) {
+	if len(changes) == 0 {
+		fmt.Println("No changes")
+	}
+	for _, change := range changes {
+		fmt.Printf("%-25s %3s -> %s\n", change.Player.FullName, change.From, change.To)
+	}
+}
+
+func PrintWeekPlan(plan *WeekPlan) {
+	for _, day := range plan.Days {
+		fmt.Println(day.Date)
+		PrintLineupChanges(day.Changes)
+	}
+
+	fmt.Println("Starts / games remaining:")
+	names := make(map[string]string)
+	for _, day := range plan.Days {
+		for _, players := range day.Starters {
+			for _, player := range players {
+				names[player.PlayerKey] = player.FullName
+			}
+		}
+	}
+	keys := []string{}
+	for key := range names {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if plan.Starts[keys[i]] != plan.Starts[keys[j]] {
+			return plan.Starts[keys[i]] > plan.Starts[keys[j]]
+		}
+		return names[keys[i]] < names[keys[j]]
+	})
+	for _, key := range keys {
+		fmt.Printf("  %-25s %d / %d\n", names[key], plan.Starts[key], plan.GamesRemaining[key])
 	}
+}
+
+func PrintStreamers(plan []StreamingOption) {
+	if len(plan) == 0 {
+		fmt.Println("No streaming options")
+	}
+	for _, option := range plan {
+		fmt.Printf("%+.2f: %s (%s), %d of %d starts: %s\n",
+			option.Delta, option.Pitcher.FullName, option.Pitcher.TeamAbbr,
+			option.UsableStarts, len(option.Starts), strings.Join(option.Starts, ", "))
 
-	return fmt.Sprintf("W:%02d S:%02d K:%03d ERA:%0.2f WHIP:%0.2f",
-		int(stats[P_WINS]),
-		0, // TODO: saves
-		int(stats[P_STRIKE_OUTS]),
-		stats[P_EARNED_RUN_AVERAGE],
-		whip)
+		stats := make([]StatID, 0, len(option.Categories))
+		for stat := range option.Categories {
+			stats = append(stats, stat)
+		}
+		sort.Slice(stats, func(i, j int) bool { return stats[i] < stats[j] })
+		changes := []string{}
+		for _, stat := range stats {
+			if math.Abs(option.Categories[stat]) >= 0.005 {
+				changes = append(changes, fmt.Sprintf("%s %+.0f%%", StatName(stat), 100*option.Categories[stat]))
+			}
+		}
+		fmt.Printf("    %s\n", FormatPitchingStats(option.Line))
+		if len(changes) > 0 {
+			fmt.Printf("    %s\n", strings.Join(changes, " "))
+		}
+	}
 }
@@ -0,0 +1,164 @@
+package folib
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeRequest struct {
+	Method string
+	Path   string
+	Body   string
+}
+
+// A stand-in for the Yahoo Fantasy API, which serves canned responses by
+// path and records every request it gets.
+type fakeYahoo struct {
+	server *httptest.Server
+	tokens *fakeTokens
+
+	mu        sync.Mutex
+	responses map[string]fakeResponse
+	// Served, in order, before the usual response.
+	queued   map[string][]fakeResponse
+	requests []fakeRequest
+	// Requests without this access token are rejected.
+	accessToken string
+}
+
+type fakeResponse struct {
+	status int
+	body   string
+}
+
+// Starts a fake Yahoo server, and returns a client which talks to it.
+func newFakeYahoo(t *testing.T) (*fakeYahoo, *YahooClient) {
+	fake := &fakeYahoo{
+		responses:   make(map[string]fakeResponse),
+		queued:      make(map[string][]fakeResponse),
+		accessToken: "token",
+		tokens:      &fakeTokens{token: "token"},
+	}
+	fake.server = httptest.NewServer(http.HandlerFunc(fake.serve))
+	t.Cleanup(fake.server.Close)
+
+	client := NewYahooClientWithTokens(fake.tokens)
+	client.cache = NewReadThroughCache(NewMemKVStore())
+	client.apiUrl = fake.server.URL
+	client.limiter = nil
+	client.retry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+	return fake, client
+}
+
+// Hands out a fixed access token, which changes when it's refreshed.
+type fakeTokens struct {
+	mu        sync.Mutex
+	token     string
+	refreshed string
+	refreshes int
+}
+
+func (f *fakeTokens) Token(ctx context.Context) (*OAuth2Token, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &OAuth2Token{AccessToken: f.token}, nil
+}
+
+func (f *fakeTokens) Refresh(ctx context.Context, rejected *OAuth2Token) (*OAuth2Token, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if rejected.AccessToken != f.token {
+		return &OAuth2Token{AccessToken: f.token}, nil
+	}
+	f.refreshes++
+	f.token = f.refreshed
+	return &OAuth2Token{AccessToken: f.token}, nil
+}
+
+// Expires the current access token, so only the refreshed one works.
+func (f *fakeYahoo) expireToken(refreshed string) {
+	f.mu.Lock()
+	f.accessToken = refreshed
+	f.mu.Unlock()
+	f.tokens.mu.Lock()
+	f.tokens.refreshed = refreshed
+	f.tokens.mu.Unlock()
+}
+
+// Serves body for GETs of path.
+func (f *fakeYahoo) respond(path, body string) {
+	f.respondTo("GET", path, body)
+}
+
+func (f *fakeYahoo) respondTo(method, path, body string) {
+	f.respondWithStatus(method, path, http.StatusOK, body)
+}
+
+func (f *fakeYahoo) respondWithStatus(method, path string, status int, body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses[method+" "+path] = fakeResponse{status: status, body: body}
+}
+
+// Serves status and body for the next request to path, e.g. to fail it
+// before the usual response.
+func (f *fakeYahoo) queue(method, path string, status int, body string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := method + " " + path
+	f.queued[key] = append(f.queued[key], fakeResponse{status: status, body: body})
+}
+
+func (f *fakeYahoo) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	f.mu.Lock()
+	authorized := r.Header.Get("Authorization") == "Bearer "+f.accessToken
+	if authorized {
+		f.requests = append(f.requests, fakeRequest{Method: r.Method, Path: r.URL.Path, Body: string(body)})
+	}
+	key := r.Method + " " + r.URL.Path
+	response, ok := f.responses[key]
+	if queued := f.queued[key]; authorized && len(queued) > 0 {
+		response, ok = queued[0], true
+		f.queued[key] = queued[1:]
+	}
+	f.mu.Unlock()
+
+	if !authorized {
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	if !ok && r.Method != "GET" {
+		response = fakeResponse{
+			status: http.StatusCreated,
+			body:   `<?xml version="1.0"?><fantasy_content></fantasy_content>`,
+		}
+		ok = true
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(response.status)
+	w.Write([]byte(response.body))
+}
+
+// Every request other than GETs.
+func (f *fakeYahoo) writes() []fakeRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	writes := []fakeRequest{}
+	for _, r := range f.requests {
+		if r.Method != "GET" {
+			writes = append(writes, r)
+		}
+	}
+	return writes
+}
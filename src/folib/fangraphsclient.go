@@ -1,190 +1,278 @@
 package folib
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
+	"log"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
 const (
 	ONE_DAY = 24 * time.Hour
+
+	// The leaderboard tab with the counting stats we need.  Others (e.g.
+	// advanced) only have rates.
+	FANGRAPHS_STANDARD = 0
 )
 
-func fgCsvUrl(year int, tabSet int, batOrPit string) string {
-	return fmt.Sprintf("http://www.fangraphs.com/leaders.aspx?pos=all&stats=%s&lg=all&qual=y&type=%d&season=%d&month=0&season1=%d&ind=0&team=0&rost=0&age=0&filter=&players=0", batOrPit, tabSet, year, year)
+// The columns which tell us a leaderboard has counting stats, by side.
+var fanGraphsPlayingTimeColumns = map[string][]string{
+	BATTING:  {"PA", "AB"},
+	PITCHING: {"IP"},
 }
 
-type FanGraphsClient struct {
-	battingStats  *map[PlayerID]StatLine
-	pitchingStats *map[PlayerID]StatLine
+// The JSON API behind FanGraphs' leaderboard pages.  (The pages themselves
+// are HTML, even with the export parameters.)
+func fgLeadersUrl(year int, tabSet int, batOrPit string) string {
+	return fmt.Sprintf("https://www.fangraphs.com/api/leaders/major-league/data?pos=all&stats=%s&lg=all&qual=0&type=%d&season=%d&season1=%d&month=0&ind=0&team=0&rost=0&age=&pageitems=2000000000", batOrPit, tabSet, year, year)
 }
 
-func (fc *FanGraphsClient) GetStat(player PlayerID, stat StatID) Stat {
-	return fc.GetStatLine(player)[stat]
-}
-
-func (fc *FanGraphsClient) GetStatLine(player PlayerID) StatLine {
-	statline, ok := (*fc.battingStats)[player]
-	if ok {
-		return statline
+// Fetches a leaderboard from the API, converted to the CSV the site exports,
+// so it's cached and parsed the same way as a saved export.  Anything that
+// isn't a leaderboard fails here, rather than being cached.
+func fanGraphsFetcher(url string) FetchFunction {
+	return func() (string, error) {
+		log.Printf("Fetching URL: '%s'", url)
+		body, err := httpGetBody(url)
+		if err != nil {
+			return "", err
+		}
+		return fanGraphsJsonToCsv(body)
 	}
+}
 
-	return (*fc.pitchingStats)[player]
+// The columns the API puts player and team names in, some as links.
+var fanGraphsNameColumns = map[string]bool{
+	"Name": true, "PlayerName": true, "Team": true, "TeamName": true, "TeamNameAbb": true,
 }
 
-func NewFanGraphsClient() (*FanGraphsClient, error) {
-	cache := NewReadThroughCache(NewFileKVStore("./cache"))
-	_, err := cache.GetAsReader(
-		urlFetcher(fgCsvUrl(2014, 0, "bat")), "fg.csv", ONE_DAY)
+func fanGraphsJsonToCsv(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if !strings.HasPrefix(body, "{") {
+		return "", fmt.Errorf("Expected JSON from FanGraphs, got: %.80q", body)
+	}
 
-	if err != nil {
-		return nil, err
+	var leaders struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&leaders); err != nil {
+		return "", fmt.Errorf("Bad JSON from FanGraphs: %s", err.Error())
+	}
+	if len(leaders.Data) == 0 {
+		return "", fmt.Errorf("No players in FanGraphs' leaderboard")
 	}
 
+	columns := []string{}
+	seen := make(map[string]bool)
+	for _, row := range leaders.Data {
+		for column := range row {
+			if !seen[column] && !fanGraphsNameColumns[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	var out strings.Builder
+	w := csv.NewWriter(&out)
+	w.Write(append([]string{"Name", "Team"}, columns...))
+	for _, row := range leaders.Data {
+		record := []string{
+			firstFanGraphsValue(row, "PlayerName", "Name"),
+			firstFanGraphsValue(row, "TeamNameAbb", "TeamName", "Team"),
+		}
+		for _, column := range columns {
+			record = append(record, fanGraphsValue(row[column]))
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	return out.String(), w.Error()
+}
 
-	return nil, fmt.Errorf("not done")
+func firstFanGraphsValue(row map[string]interface{}, columns ...string) string {
+	for _, column := range columns {
+		if v := fanGraphsValue(row[column]); v != "" {
+			return v
+		}
+	}
+	return ""
 }
 
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+func fanGraphsValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(htmlTag.ReplaceAllString(v, ""))
+	case json.Number:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
 
-/*
-type ZipsClient struct {
+type FanGraphsClient struct {
 	battingStats  *map[PlayerID]StatLine
 	pitchingStats *map[PlayerID]StatLine
+	players       []PlayerRecord
 }
 
-func (zc *ZipsClient) GetStat(player PlayerID, stat StatID) Stat {
-	return zc.GetStatLine(player)[stat]
+func (fc *FanGraphsClient) Players() []PlayerRecord {
+	return fc.players
 }
 
-func (zc *ZipsClient) GetStatLine(player PlayerID) StatLine {
-	statline, ok := (*zc.battingStats)[player]
+func (fc *FanGraphsClient) GetStat(player PlayerID, stat StatID) Stat {
+	return fc.GetStatLine(player)[stat]
+}
+
+func (fc *FanGraphsClient) GetStatLine(player PlayerID) StatLine {
+	statline, ok := (*fc.battingStats)[player]
 	if ok {
 		return statline
 	}
 
-	return (*zc.pitchingStats)[player]
+	return (*fc.pitchingStats)[player]
 }
 
-func NewZipsClient() (*ZipsClient, error) {
-	battingStats, err := indexBattingStats()
+// Fetches the batting and pitching leaderboards for a season.  Since these
+// change daily during the season, they're only cached for a day.
+func NewFanGraphsClient(season int, tab int) (*FanGraphsClient, error) {
+	cache := NewReadThroughCache(NewFileKVStore("./cache"))
+
+	batting, err := cache.GetAsReader(
+		fanGraphsFetcher(fgLeadersUrl(season, tab, "bat")),
+		fmt.Sprintf("fgapi%d_%d_bat.csv", season, tab),
+		ONE_DAY)
 	if err != nil {
 		return nil, err
 	}
 
-	pitchingStats, err := indexPitchingStats()
+	pitching, err := cache.GetAsReader(
+		fanGraphsFetcher(fgLeadersUrl(season, tab, "pit")),
+		fmt.Sprintf("fgapi%d_%d_pit.csv", season, tab),
+		ONE_DAY)
 	if err != nil {
 		return nil, err
 	}
 
-	return &ZipsClient{battingStats: battingStats, pitchingStats: pitchingStats}, nil
+	return newFanGraphsClientFromCsv(batting, pitching)
 }
 
-func mapColumnNameToBattingStat() map[ColName]StatID {
-	return map[ColName]StatID{
-		"AB":  B_AT_BATS,
-		"BA":  B_BATTING_AVG,
-		"CS":  B_CAUGHT_STEALING,
-		"2B":  B_DOUBLES,
-		"G":   B_GAMES,
-		"H":   B_HITS,
-		"HR":  B_HOME_RUNS,
-		"OBP": B_ON_BASE_PCT,
-		"PA":  B_PLATE_APPS,
-		"R":   B_RUNS,
-		"RBI": B_RUNS_BATTED_IN,
-		"SLG": B_SLUGGING,
-		"SB":  B_STOLEN_BASES,
-		"K":   B_STRIKE_OUTS,
-		"3B":  B_TRIPLES,
-		"BB":  B_WALKS,
+// How much of the season the stats cover, judging by the most games any
+// batter has played, e.g. 0.5 at the All-Star break.  Zero before the season.
+func (fc *FanGraphsClient) SeasonFraction() float64 {
+	games := Stat(0)
+	for _, line := range *fc.battingStats {
+		games = max(games, line[B_GAMES])
 	}
+	return math.Min(1, float64(games)/SEASON_GAMES)
 }
 
-func mapColumnNameToPitchingStat() map[ColName]StatID {
-	return map[ColName]StatID{
-		"ER":  P_EARNED_RUNS,
-		"ERA": P_EARNED_RUN_AVERAGE,
-		"G":   P_GAMES,
-		"H":   P_HITS,
-		"HR":  P_HOME_RUNS,
-		"IP":  P_INNINGS,
-		"L":   P_LOSSES,
-		"R":   P_RUNS,
-		"GS":  P_STARTS,
-		"SO":  P_STRIKE_OUTS,
-		"BB":  P_WALKS,
-		"W":   P_WINS,
+func newFanGraphsClientFromCsv(batting, pitching io.Reader) (*FanGraphsClient, error) {
+	battingRows, err := indexFanGraphsStats(batting, mapFanGraphsColumnToStat(BATTING), BATTING)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading FanGraphs batting: %s", err.Error())
 	}
-}
 
-func mapBattingStatToColumnIndex(
-	colNames []string, columnNameToStat map[ColName]StatID) map[StatID]ColIndex {
-	statToColumnIndex := map[StatID]ColIndex{}
-	for i := range colNames {
-		colName := ColName(colNames[i])
-		if statId, ok := columnNameToStat[colName]; ok {
-			statToColumnIndex[statId] = ColIndex(i)
-		}
+	pitchingRows, err := indexFanGraphsStats(pitching, mapFanGraphsColumnToStat(PITCHING), PITCHING)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading FanGraphs pitching: %s", err.Error())
 	}
 
-	return statToColumnIndex
+	battingStats, pitchingStats, players := indexPlayerRows(battingRows, pitchingRows)
+	return &FanGraphsClient{
+		battingStats:  battingStats,
+		pitchingStats: pitchingStats,
+		players:       players,
+	}, nil
 }
 
-func urlFetcher(url string) FetchFunction {
-	return func() (string, error) {
-		log.Printf("Fetching URL: '%s'", url)
-		return httpGetBody(url)
+// Unlike ZiPS, FanGraphs exports put the player's name in a "Name" column
+// (which isn't necessarily first), leave blanks for stats a player doesn't
+// have, and report innings in box score notation.
+func indexFanGraphsStats(f io.Reader, columnNameToStat map[ColName]StatID, side string) ([]playerRow, error) {
+	// Excel-friendly exports start with a byte order mark.
+	br := bufio.NewReader(f)
+	if first, _, err := br.ReadRune(); err == nil && first != '\ufeff' {
+		br.UnreadRune()
 	}
-}
 
-func indexBattingStats() (*map[PlayerID]StatLine, error) {
-	cache := NewReadThroughCache(NewFileKVStore("./cache"))
-	cacheReader, err := cache.GetAsReader(
-		urlFetcher(BATTERS_URL), BATTERS_CSV, ONE_MONTH)
+	// FanGraphs answers bad requests with a web page.
+	if start, _ := br.Peek(512); bytes.HasPrefix(bytes.TrimSpace(start), []byte("<")) {
+		return nil, fmt.Errorf("Expected CSV, got HTML: %.80q", bytes.TrimSpace(start))
+	}
 
+	r := csv.NewReader(br)
+	recs, err := r.ReadAll()
 	if err != nil {
 		return nil, err
 	}
 
-	return indexStats(cacheReader, mapColumnNameToBattingStat())
-}
-
-func indexPitchingStats() (*map[PlayerID]StatLine, error) {
-	cache := NewReadThroughCache(NewFileKVStore("./cache"))
-	cacheReader, err := cache.GetAsReader(
-		urlFetcher(PITCHERS_URL), PITCHERS_CSV, ONE_MONTH)
-
-	if err != nil {
-		return nil, err
+	if len(recs) == 0 {
+		return nil, fmt.Errorf("Empty CSV")
 	}
 
-	return indexStats(cacheReader, mapColumnNameToPitchingStat())
-}
+	header := recs[0]
 
-func indexStats(f io.Reader, columnNameToStat map[ColName]StatID) (*map[PlayerID]StatLine, error) {
-	r := csv.NewReader(f)
-	r.TrailingComma = true // Ok to end in trailing comma
-	recs, err := r.ReadAll()
+	nameIndex := findColumn(header, "Name")
+	if nameIndex == -1 {
+		return nil, fmt.Errorf("No 'Name' column in: %v", header)
+	}
+	teamIndex := findColumn(header, "Team")
+	idIndex := findColumn(header, "playerid")
+	mlbamIndex := findColumn(header, "xMLBAMID", "MLBAMID")
 
-	if err != nil {
-		return nil, err
+	if columns := fanGraphsPlayingTimeColumns[side]; findColumn(header, columns...) == -1 {
+		return nil, fmt.Errorf("No counting stats (e.g. %s) in the leaderboard; use the standard tab (%d)",
+			columns[0], FANGRAPHS_STANDARD)
 	}
 
-	statToColumnIndex := mapBattingStatToColumnIndex(recs[0], columnNameToStat)
+	statToColumnIndex := mapBattingStatToColumnIndex(header, columnNameToStat)
 
-	statIndex := map[PlayerID]StatLine{}
+	rows := []playerRow{}
 	for i := 1; i < len(recs); i++ {
 		statLine := make(StatLine)
 		for stat, index := range statToColumnIndex {
-			statStr := recs[i][index]
+			statStr := strings.TrimSpace(recs[i][index])
+			if statStr == "" {
+				continue
+			}
 			stat64, err := strconv.ParseFloat(statStr, 64)
 			if err != nil {
-				return nil, err
+				return nil, fmt.Errorf("Row %d, column %s: %s", i, header[index], err.Error())
+			}
+			if stat == P_INNINGS {
+				stat64 = fromBaseballInnings(stat64)
 			}
 			statLine[stat] = Stat(stat64)
 		}
-		statIndex[PlayerID(recs[i][0])] = statLine
+		record := PlayerRecord{Name: recs[i][nameIndex], Side: side}
+		if teamIndex != -1 {
+			record.Team = recs[i][teamIndex]
+		}
+		if idIndex != -1 {
+			record.FanGraphsID = recs[i][idIndex]
+		}
+		if mlbamIndex != -1 {
+			record.MLBAMID = recs[i][mlbamIndex]
+		}
+		rows = append(rows, playerRow{record: record, stats: statLine})
 	}
 
-	return &statIndex, nil
+	return rows, nil
 }
-*/
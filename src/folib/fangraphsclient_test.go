@@ -0,0 +1,161 @@
+package folib
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func loadFanGraphsFixtures(t *testing.T) *FanGraphsClient {
+	batting, err := os.Open("testdata/fg_batting.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer batting.Close()
+
+	pitching, err := os.Open("testdata/fg_pitching.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pitching.Close()
+
+	client, err := newFanGraphsClientFromCsv(batting, pitching)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestFanGraphsBatting(t *testing.T) {
+	var client StatsClient = loadFanGraphsFixtures(t)
+
+	line := client.GetStatLine("Miguel Cabrera")
+	expected := StatLine{
+		B_AT_BATS:      555,
+		B_HITS:         193,
+		B_HOME_RUNS:    44,
+		B_STOLEN_BASES: 3,
+		B_STRIKE_OUTS:  94,
+		B_BATTING_AVG:  .348,
+	}
+	for stat, value := range expected {
+		if line[stat] != value {
+			t.Errorf("%s: expected %f, got %f", StatName(stat), value, line[stat])
+		}
+	}
+
+	if client.GetStat("Mike Trout", B_STOLEN_BASES) != 33 {
+		t.Errorf("Expected Trout to have 33 SB, got: %f", client.GetStat("Mike Trout", B_STOLEN_BASES))
+	}
+}
+
+func TestFanGraphsPitching(t *testing.T) {
+	client := loadFanGraphsFixtures(t)
+
+	line := client.GetStatLine("Clayton Kershaw")
+	if line[P_STRIKE_OUTS] != 232 || line[P_EARNED_RUN_AVERAGE] != 1.83 || line[P_INNINGS] != 236 {
+		t.Errorf("Wrong line for Kershaw: %v", line)
+	}
+
+	if saves := client.GetStat("Craig Kimbrel", P_SAVES); saves != 50 {
+		t.Errorf("Expected Kimbrel to have 50 saves, got: %f", saves)
+	}
+
+	innings := client.GetStat("Max Scherzer", P_INNINGS)
+	if !closeEnough(innings, 214+1.0/3) {
+		t.Errorf("Expected 214 1/3 innings for Scherzer, got: %f", innings)
+	}
+}
+
+func TestFanGraphsBlankStatsAreMissing(t *testing.T) {
+	client := loadFanGraphsFixtures(t)
+
+	pitching := client.GetStatLine("Chris Young [Mariners]")
+	if _, ok := pitching[P_INNINGS]; ok {
+		t.Errorf("Blank innings should be missing, got: %v", pitching)
+	}
+}
+
+func TestFanGraphsDisambiguatesDuplicateNames(t *testing.T) {
+	client := loadFanGraphsFixtures(t)
+
+	if client.GetStat("Chris Young [Athletics]", B_HOME_RUNS) != 12 {
+		t.Errorf("Expected the batter Chris Young, got: %v", client.GetStatLine("Chris Young [Athletics]"))
+	}
+	if len(client.GetStatLine("Chris Young")) != 0 {
+		t.Errorf("An ambiguous name shouldn't match anyone, got: %v", client.GetStatLine("Chris Young"))
+	}
+
+	found := 0
+	for _, record := range client.Players() {
+		if record.Name == "Chris Young" {
+			found++
+		}
+		if record.ID == "Mike Trout" && (record.FanGraphsID != "10155" || record.Team != "Angels" || record.Side != BATTING) {
+			t.Errorf("Wrong record for Trout: %v", record)
+		}
+	}
+	if found != 2 {
+		t.Errorf("Expected two Chris Youngs, found %d", found)
+	}
+}
+
+func TestFanGraphsJsonToCsv(t *testing.T) {
+	batting, err := fanGraphsJsonToCsv(`{"data": [
+		{"Name": "<a href=\"statss.aspx?playerid=10155\">Mike Trout</a>", "PlayerName": "Mike Trout",
+		 "Team": "<a href=\"leaders.aspx?team=1\">LAA</a>", "TeamNameAbb": "LAA",
+		 "playerid": 10155, "xMLBAMID": 545361, "AB": 589, "H": 190, "HR": 27, "AVG": 0.3225806451612903, "SB": null}
+	], "totalCount": 1}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pitching, err := fanGraphsJsonToCsv(`{"data": [
+		{"Name": "Clayton Kershaw", "Team": "LAD", "playerid": 2036, "IP": 236.1, "ER": 48, "ERA": 1.83}
+	]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := newFanGraphsClientFromCsv(strings.NewReader(batting), strings.NewReader(pitching))
+	if err != nil {
+		t.Fatal(err)
+	}
+	trout := client.GetStatLine("Mike Trout")
+	if trout[B_HOME_RUNS] != 27 || trout[B_AT_BATS] != 589 {
+		t.Errorf("Wrong line for Trout: %v", trout)
+	}
+	if _, ok := trout[B_STOLEN_BASES]; ok {
+		t.Errorf("Expected a null stat to be missing, got %v", trout[B_STOLEN_BASES])
+	}
+	if record := client.Players()[0]; record.Team != "LAA" || record.FanGraphsID != "10155" || record.MLBAMID != "545361" {
+		t.Errorf("Expected the plain team and id, got %v", record)
+	}
+	if ip := client.GetStatLine("Clayton Kershaw")[P_INNINGS]; !closeEnough(ip, 236.333) {
+		t.Errorf("Expected 236 1/3 innings, got %f", ip)
+	}
+}
+
+func TestFanGraphsRejectsWebPages(t *testing.T) {
+	page := "<!DOCTYPE html>\n<html><head><title>Leaderboards</title></head></html>"
+	if _, err := fanGraphsJsonToCsv(page); err == nil || !strings.Contains(err.Error(), "Expected JSON") {
+		t.Errorf("Expected an error for HTML instead of JSON, got: %v", err)
+	}
+	if _, err := fanGraphsJsonToCsv(`{"data": []}`); err == nil {
+		t.Errorf("Expected an error for an empty leaderboard")
+	}
+
+	_, err := newFanGraphsClientFromCsv(strings.NewReader(page), strings.NewReader(page))
+	if err == nil || !strings.Contains(err.Error(), "Expected CSV") {
+		t.Errorf("Expected an error for HTML instead of CSV, got: %v", err)
+	}
+}
+
+func TestFanGraphsRejectsTabsWithoutCountingStats(t *testing.T) {
+	// e.g. the advanced tab, which only has rates.
+	advanced := "Name,Team,BB%,K%,ISO,wOBA,playerid\nMike Trout,LAA,0.11,0.19,0.2,0.402,10155\n"
+	pitching := "Name,Team,IP,ER,ERA\nClayton Kershaw,LAD,236.1,48,1.83\n"
+	_, err := newFanGraphsClientFromCsv(strings.NewReader(advanced), strings.NewReader(pitching))
+	if err == nil || !strings.Contains(err.Error(), "No counting stats") {
+		t.Errorf("Expected an error for a leaderboard without counting stats, got: %v", err)
+	}
+}
@@ -1,25 +1,123 @@
 package folib
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"sort"
 )
 
-const (
-	LEAGUE_ID = 5181
-)
-
 type FO struct {
 	yahoo       *YahooClient
 	projections StatsClient
+	league      *LeagueContext
+	settings    *LeagueSettings
+	players     *PlayerResolver
+	schedule    *Schedule // Optional
+	// Optional: ids resolved up front, by Yahoo player key.  See
+	// withPlayerIDs.
+	playerIDs map[string]PlayerID
+}
+
+func NewFO(yahoo *YahooClient, projections StatsClient, league *LeagueContext) *FO {
+	records := []PlayerRecord{}
+	if directory, ok := projections.(PlayerDirectory); ok {
+		records = directory.Players()
+	}
+
+	return &FO{
+		yahoo:       yahoo,
+		projections: projections,
+		league:      league,
+		players:     NewPlayerResolver(records, []PlayerOverride{}),
+	}
+}
+
+// Loads hand-maintained matches for players we can't match automatically.
+// See LoadPlayerOverrides for the file format.
+func (fo *FO) LoadPlayerOverrides(filename string) error {
+	overrides, err := LoadPlayerOverrides(filename)
+	if err != nil {
+		return err
+	}
+	for _, o := range overrides {
+		fo.players.AddOverride(o)
+	}
+	return nil
+}
+
+// Writes every override we have, plus the players we couldn't match so far
+// and who they might be, to be filled in by hand.
+func (fo *FO) SavePlayerOverrides(filename string) error {
+	return SavePlayerOverrides(filename, fo.players.Overrides(), fo.players.Unmatched())
 }
 
-func NewFO(yahoo *YahooClient, projections StatsClient) *FO {
-	return &FO{yahoo: yahoo, projections: projections}
+// Scales projections over days or weeks by the MLB schedule, rather than
+// assuming every team plays every day.
+func (fo *FO) SetSchedule(schedule *Schedule) {
+	fo.schedule = schedule
 }
 
-func (fo *FO) Optimize() {
+// The id our projections know a Yahoo player by.
+func (fo *FO) playerID(player YahooPlayer) PlayerID {
+	if id, ok := fo.playerIDs[player.PlayerKey]; ok {
+		return id
+	}
+	return fo.players.PlayerID(player)
+}
+
+// A copy of fo which already knows the ids of everyone on the rosters, so
+// goroutines working on them don't all wait on the resolver.
+func (fo *FO) withPlayerIDs(rosters map[TeamID][]YahooPlayer) *FO {
+	ids := make(map[string]PlayerID)
+	for _, roster := range rosters {
+		for _, player := range roster {
+			if player.PlayerKey != "" {
+				ids[player.PlayerKey] = fo.players.PlayerID(player)
+			}
+		}
+	}
+	search := *fo
+	search.playerIDs = ids
+	return &search
+}
+
+// Tries to match every rostered player in the league to our projections, and
+// reports the ones we couldn't.
+func (fo *FO) ReportUnmatchedPlayers(ctx context.Context) error {
+	rosters, err := fo.yahoo.LeagueRosters(ctx, fo.league.LeagueKey)
+	if err != nil {
+		return err
+	}
+
+	for _, roster := range *rosters {
+		for _, player := range roster {
+			fo.players.Resolve(player)
+		}
+	}
+
+	unmatched := fo.players.Unmatched()
+	fmt.Printf("%d unmatched players\n", len(unmatched))
+	for _, u := range unmatched {
+		fmt.Printf("%s (%s, %s %s)\n",
+			u.Player.FullName, u.Player.PlayerKey, u.Player.TeamAbbr, u.Player.PositionType)
+		for _, candidate := range u.Candidates {
+			fmt.Printf("    maybe: %s (%s %s)\n", candidate.ID, candidate.Team, candidate.Side)
+		}
+	}
+	return nil
+}
+
+// Every team's current roster.
+func (fo *FO) LeagueRosters(ctx context.Context) (map[TeamID][]YahooPlayer, error) {
+	rosters, err := fo.yahoo.LeagueRosters(ctx, fo.league.LeagueKey)
+	if err != nil {
+		return nil, err
+	}
+	return *rosters, nil
+}
+
+func (fo *FO) Optimize(ctx context.Context) {
 	log.Println("folib.optimize")
 	
 //	b, err := fo.yahoo.Try(
@@ -40,17 +138,22 @@ func (fo *FO) Optimize() {
 
 //	log.Println(b);
 
-	rosters, err := fo.yahoo.LeagueRosters()
+	err := fo.loadSettings(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rosters, err := fo.yahoo.LeagueRosters(ctx, fo.league.LeagueKey)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	err = fo.scoreTrade(rosters, "Matt Cain", "Troy Tulowitzki")
+	err = fo.scoreTrade(ctx, rosters, "Matt Cain", "Troy Tulowitzki")
 	if err != nil {
 		log.Fatal(err)
 	}
 
-//	teamStats, err := fo.yahoo.CurrentStats()
+//	teamStats, err := fo.yahoo.CurrentStats(ctx, fo.league.LeagueKey)
 //	if err != nil {
 //		log.Fatal(err)
 //	}
@@ -58,98 +161,72 @@ func (fo *FO) Optimize() {
 //	teamProjections := projectLeague(rosters)
 //
 //	fmt.Printf("Projections\n")
-//	printScores(scoreLeague(teamProjections, scoringCategories()))
+//	printScores(scoreLeague(teamProjections, fo.settings.Scorer()))
 //
 //	fmt.Printf("\nActuals\n")
-//	printScores(scoreLeague(*teamStats, scoringCategories()))
+//	printScores(scoreLeague(*teamStats, fo.settings.Scorer()))
+}
+
+func (fo *FO) loadSettings(ctx context.Context) error {
+	if fo.settings != nil {
+		return nil
+	}
+
+	settings, err := fo.yahoo.GetLeagueSettings(ctx, fo.league.LeagueKey)
+	if err != nil {
+		return err
+	}
+	fo.settings = settings
+	return nil
 }
 
 func (fo *FO) projectLeague(rosters *map[TeamID][]YahooPlayer) map[TeamID]StatLine {
 	teamProjections := make(map[TeamID]StatLine)
 	for i := range *rosters {
-		teamProjections[i] = fo.projectRoster((*rosters)[i], .9)
+		teamProjections[i] = fo.projectRoster((*rosters)[i], 0)
 	}
 	return teamProjections
 }
 
-func (fo *FO) scoreTrade(rosters *map[TeamID][]YahooPlayer, p1, p2 PlayerID) error {
-	beforeProjections := fo.projectLeague(rosters)
-	// copy?
-		err, t1, t2 := trade(rosters, p1, p2)
+func (fo *FO) scoreTrade(ctx context.Context, rosters *map[TeamID][]YahooPlayer, p1, p2 PlayerID) error {
+	t1, err := fo.teamOf(*rosters, p1)
 	if err != nil {
 		return err
 	}
-	afterProjections := fo.projectLeague(rosters)
-
-	fmt.Printf("Before\n")
-	beforeScores := scoreLeague(beforeProjections, scoringCategories())
-	printScores(beforeScores)
-	fmt.Printf("TEAM %d: %s -> %s\n", t1, FormatBattingStats(beforeProjections[t1]), FormatBattingStats(afterProjections[t1]))
-	fmt.Printf("TEAM %d: %s -> %s\n", t1, FormatPitchingStats(beforeProjections[t1]), FormatPitchingStats(afterProjections[t1]))
-	fmt.Printf("TEAM %d: %s -> %s\n", t2, FormatBattingStats(beforeProjections[t2]), FormatBattingStats(afterProjections[t2]))
-	fmt.Printf("TEAM %d: %s -> %s\n", t2, FormatPitchingStats(beforeProjections[t2]), FormatPitchingStats(afterProjections[t2]))
-
-	fmt.Printf("After\n")
-	afterScores := scoreLeague(afterProjections, scoringCategories())
-	printScores(afterScores)
-
-	fmt.Printf("Delta\n")
-	for t := range(beforeProjections) {
-		fmt.Printf("TEAM %d: %f\n", t, afterScores[t] - beforeScores[t])
-	}
-
-	return nil
-}
-
-func trade(rosters *map[TeamID][]YahooPlayer, p1, p2 PlayerID) (error, TeamID, TeamID) {
-	t1, i1 := TeamID(-1), -1
-	t2, i2 := TeamID(-1), -1
-	for team, roster := range(*rosters) {
-		for i, player := range(roster) {
-			c := PlayerID(player.FullName)
-			if p1 == c {
-				t1, i1 = team, i
-			} else if (p2 == c) {
-				t2, i2 = team, i
-			}
-		}
+	t2, err := fo.teamOf(*rosters, p2)
+	if err != nil {
+		return err
 	}
 
-	if t1 == -1 || i1 == -1 || t2 == -1 || i2 == 1 {
-		return fmt.Errorf("Bad lookup: %d %d %d %d", t1, i1, t2, i2), -1, -1
+	evaluation, err := fo.EvaluateTrade(ctx, *rosters, TradeProposal{
+		A: TradeSide{Team: t1, Gives: []PlayerID{p1}},
+		B: TradeSide{Team: t2, Gives: []PlayerID{p2}},
+	})
+	if err != nil {
+		return err
 	}
 
-	fmt.Printf("Moving %s from team %d to team %d\n", p1, t1, t2)
-	fmt.Printf("Moving %s from team %d to team %d\n", p2, t2, t1)
-
-	(*rosters)[t1][i1], (*rosters)[t2][i2] = (*rosters)[t2][i2], (*rosters)[t1][i1]
-	return nil, t1, t2
+	PrintTradeEvaluation(evaluation)
+	return nil
 }
 
-func (fo *FO) projectPlayers(players []YahooPlayer, seasonComplete float32) map[PlayerID]StatLine {
+// Projects each player over the part of the season that's left to play,
+// given the fraction of it (0 to 1) that's already complete.
+func (fo *FO) projectPlayers(players []YahooPlayer, seasonComplete float64) map[PlayerID]StatLine {
 	result := make(map[PlayerID]StatLine)
 
 	for i := range players {
-		id := PlayerID(players[i].FullName)
-		result[id] = fo.projections.GetStatLine(id)
+		id := fo.playerID(players[i])
+		result[id] = scaleStatLine(fo.projections.GetStatLine(id), 1-seasonComplete)
 	}
 
 	return result
 }
 
-func (fo *FO) projectRoster(roster []YahooPlayer, seasonComplete float32) StatLine {
-	starterStats := make([]StatLine, 0)
-	starters := fo.selectStarters(roster)
-
-	for pos := range starters {
-		for play := range starters[pos] {
-			player := starters[pos][play]
-			stats := fo.projections.GetStatLine(PlayerID(player.FullName))
-			starterStats = append(starterStats, stats)
-		}
-	}
-
-	return merge(starterStats)
+// Projects the combined stats of a roster's starters over the rest of the
+// season.
+func (fo *FO) projectRoster(roster []YahooPlayer, seasonComplete float64) StatLine {
+	return fo.projectStarters(roster, 1-seasonComplete)
 }
 
 type TeamLeaderEntry struct {
@@ -180,78 +257,27 @@ func SortedLeaders(scores map[PlayerID]float32) TeamLeaders {
 	return l
 }
 
-func indexByName(players []YahooPlayer) map[PlayerID]YahooPlayer {
-	index := make(map[PlayerID]YahooPlayer)
-	for _, player := range players {
-		index[PlayerID(player.FullName)] = player
-	}
-	return index
-}
+func (fo *FO) selectStarters(roster []YahooPlayer, topology RosterTopology) map[Position][]YahooPlayer {
+	values := fo.starterValues(roster)
 
-func (fo *FO) selectStarters(roster []YahooPlayer) map[Position][]YahooPlayer {
-	positionCounts := rosterTopology()
-	statMap := fo.projectPlayers(roster, 1.0)
-	leaders := SortedLeaders(scoreTeam(statMap, scoringCategories()))
 	starters := make(map[Position][]YahooPlayer)
-	index := indexByName(roster)
-
-	for _, entry := range leaders {
-		player := index[entry.ID]
-		starting := false
-		for _, posStr := range player.Position {
-			pos := Position(posStr)
-			if positionCounts[pos] > 0 {
-				starters[pos] = append(starters[pos], player)
-				positionCounts[pos]--
-				fmt.Printf("%s is starting at %s\n", player.FullName, pos)
-				starting = true
-				break
-			}
-		}
-		if !starting {
-			fmt.Printf("%s is NOT starting\n", player.FullName)
+	for pos, indices := range optimalLineup(roster, values, topology) {
+		for _, i := range indices {
+			starters[pos] = append(starters[pos], roster[i])
 		}
 	}
 
-	fmt.Println("---")
-
 	return starters
 }
 
-func rosterTopology() map[Position]int {
-	return map[Position]int{
-		"C":    1,
-		"1B":   1,
-		"2B":   1,
-		"3B":   1,
-		"SS":   1,
-		"OF":   3,
-		"Util": 3,
-		"SP":   4,
-		"RP":   2,
-		"P":    2,
-	}
-}
+// How valuable each player on the roster is as a starter.
+func (fo *FO) starterValues(roster []YahooPlayer) []float32 {
+	statMap := fo.projectPlayers(roster, 0)
+	scores := scoreTeam(statMap, fo.settings.Scorer())
 
-func scoringCategories() map[StatID]struct{} {
-	return map[StatID]struct{}{
-		B_BATTING_AVG:    struct{}{},
-		B_HOME_RUNS:      struct{}{},
-		B_RUNS:           struct{}{},
-		B_RUNS_BATTED_IN: struct{}{},
-		B_STOLEN_BASES:   struct{}{},
-
-		P_WINS:               struct{}{},
-		P_SAVES:              struct{}{},
-		P_EARNED_RUN_AVERAGE: struct{}{},
-		P_WHIP:               struct{}{},
-		P_STRIKE_OUTS:        struct{}{},
+	values := make([]float32, len(roster))
+	for i, player := range roster {
+		values[i] = scores[fo.playerID(player)]
 	}
+	return values
 }
-
-// SimulateSeason
-// - Fetch Current Stats
-// - Fetch Rosters
-// - Fetch Projections
-// - Scale Projections
-// - Compute Final Stats
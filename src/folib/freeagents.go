@@ -0,0 +1,138 @@
+package folib
+
+import (
+	"context"
+	"sort"
+)
+
+// The positions we look for free agents at, by default.
+var pickupPositions = []string{"C", "1B", "2B", "3B", "SS", "OF", "SP", "RP"}
+
+// Adding a free agent, and who to drop to make room for them.
+type PickupRecommendation struct {
+	Add YahooPlayer
+	// Empty if there's already room on the roster.
+	Drop YahooPlayer
+
+	// How many roto points I gain.
+	Delta float32
+	// How much more valuable the player added is than the player dropped,
+	// which breaks ties between pickups that don't change the standings.
+	ValueGain float32
+
+	Before StatLine
+	After  StatLine
+}
+
+// Finds the best free agents to pick up at each position (or the default
+// positions, if none are given).  Each of the top count free agents is tried
+// in place of each of my active players, and the best few add/drop pairs at
+// each position are returned, best first.
+func (fo *FO) RecommendPickups(ctx context.Context, positions []string, count, perPosition int) (map[string][]PickupRecommendation, error) {
+	err := fo.loadSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(positions) == 0 {
+		positions = pickupPositions
+	}
+
+	rosters, err := fo.LeagueRosters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	freeAgents := make(map[string][]YahooPlayer)
+	for _, position := range positions {
+		players, err := fo.yahoo.GetFreeAgents(ctx, fo.league.LeagueKey, position, count)
+		if err != nil {
+			return nil, err
+		}
+		freeAgents[position] = players
+	}
+
+	return fo.recommendPickups(rosters, freeAgents, perPosition), nil
+}
+
+func (fo *FO) recommendPickups(rosters map[TeamID][]YahooPlayer, freeAgents map[string][]YahooPlayer, perPosition int) map[string][]PickupRecommendation {
+	me := fo.league.MyTeamID
+	mine := rosters[me]
+	before := fo.projectLeague(&rosters)
+	beforeScores := scoreLeague(before, fo.settings.Scorer())
+
+	// Value everyone together, so free agents can be compared to rostered
+	// players.
+	everyone := copyRosters(rosters)
+	for _, players := range freeAgents {
+		everyone[-1] = append(everyone[-1], players...)
+	}
+	values := fo.playerValues(everyone)
+
+	// Dropping nobody is an option, if there's room.
+	drops := []YahooPlayer{}
+	if limit := fo.settings.Topology.ActiveSize(); limit <= 0 || activeCount(mine) < limit {
+		drops = append(drops, YahooPlayer{})
+	}
+	for _, player := range mine {
+		if !isInactive(player) {
+			drops = append(drops, player)
+		}
+	}
+
+	recommendations := make(map[string][]PickupRecommendation)
+	for position, players := range freeAgents {
+		for _, add := range players {
+			best := PickupRecommendation{}
+			found := false
+			for _, drop := range drops {
+				after := make(map[TeamID]StatLine)
+				for team, line := range before {
+					after[team] = line
+				}
+				after[me] = fo.projectRoster(withPickup(mine, add, drop), 0)
+
+				r := PickupRecommendation{
+					Add:       add,
+					Drop:      drop,
+					Delta:     scoreLeague(after, fo.settings.Scorer())[me] - beforeScores[me],
+					ValueGain: values[add.PlayerKey] - values[drop.PlayerKey],
+					Before:    before[me],
+					After:     after[me],
+				}
+				if !found || betterPickup(r, best) {
+					best, found = r, true
+				}
+			}
+			if found {
+				recommendations[position] = append(recommendations[position], best)
+			}
+		}
+
+		sort.SliceStable(recommendations[position], func(i, j int) bool {
+			return betterPickup(recommendations[position][i], recommendations[position][j])
+		})
+		if perPosition > 0 && len(recommendations[position]) > perPosition {
+			recommendations[position] = recommendations[position][:perPosition]
+		}
+	}
+	return recommendations
+}
+
+func betterPickup(a, b PickupRecommendation) bool {
+	if a.Delta != b.Delta {
+		return a.Delta > b.Delta
+	}
+	return a.ValueGain > b.ValueGain
+}
+
+// A copy of the roster with one player added, and (unless drop is empty)
+// another dropped.
+func withPickup(roster []YahooPlayer, add, drop YahooPlayer) []YahooPlayer {
+	result := []YahooPlayer{}
+	for _, player := range roster {
+		if drop.PlayerKey == "" || player.PlayerKey != drop.PlayerKey {
+			result = append(result, player)
+		}
+	}
+	return append(result, add)
+}
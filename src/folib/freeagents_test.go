@@ -0,0 +1,93 @@
+package folib
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestFetchPages(t *testing.T) {
+	available := []YahooPlayer{}
+	for i := 0; i < 60; i++ {
+		available = append(available, YahooPlayer{PlayerKey: fmt.Sprintf("p.%d", i)})
+	}
+
+	requests := [][2]int{}
+	fetch := func(start, n int) ([]YahooPlayer, error) {
+		requests = append(requests, [2]int{start, n})
+		end := min(start+n, len(available))
+		return available[start:end], nil
+	}
+
+	players, err := fetchPages(55, 25, fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(players) != 55 || players[54].PlayerKey != "p.54" {
+		t.Errorf("Expected the first 55 players, got %d", len(players))
+	}
+	if !reflect.DeepEqual(requests, [][2]int{{0, 25}, {25, 25}, {50, 5}}) {
+		t.Errorf("Wrong pages requested: %v", requests)
+	}
+
+	// Stop when we run out of players.
+	requests = [][2]int{}
+	players, err = fetchPages(100, 25, fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(players) != 60 {
+		t.Errorf("Expected all 60 players, got %d", len(players))
+	}
+	if len(requests) != 3 {
+		t.Errorf("Expected to stop after a short page, got: %v", requests)
+	}
+}
+
+func TestRecommendPickups(t *testing.T) {
+	fo := testFO(fakeStatsClient{
+		"Slugger": {B_HOME_RUNS: 40},
+		"Scrub":   {B_HOME_RUNS: 1},
+		"Masher":  {B_HOME_RUNS: 35},
+		"Speedy":  {B_STOLEN_BASES: 30},
+		"Burner":  {B_STOLEN_BASES: 40},
+		"Dud":     {B_HOME_RUNS: 2},
+	}, &LeagueSettings{
+		Categories: ScoringCategories{
+			B_HOME_RUNS:    HIGHER_IS_BETTER,
+			B_STOLEN_BASES: HIGHER_IS_BETTER,
+		},
+		Topology: RosterTopology{Starters: map[Position]int{"OF": 2}},
+	})
+	fo.league = &LeagueContext{MyTeamID: 1}
+
+	rosters := map[TeamID][]YahooPlayer{
+		1: {hitter("p.1", "Slugger"), hitter("p.2", "Scrub")},
+		2: {hitter("p.3", "Masher"), hitter("p.4", "Speedy")},
+	}
+	freeAgents := map[string][]YahooPlayer{
+		"OF": {hitter("p.5", "Dud"), hitter("p.6", "Burner")},
+	}
+
+	recommendations := fo.recommendPickups(rosters, freeAgents, 0)
+
+	of := recommendations["OF"]
+	if len(of) != 2 {
+		t.Fatalf("Expected a recommendation for each free agent, got: %v", of)
+	}
+	if of[0].Add.FullName != "Burner" || of[0].Drop.FullName != "Scrub" || of[0].Delta != 1 {
+		t.Errorf("Expected to add Burner for Scrub, for a point, got: %s for %s (%f)",
+			of[0].Add.FullName, of[0].Drop.FullName, of[0].Delta)
+	}
+	if of[1].Add.FullName != "Dud" || of[1].Drop.FullName != "Scrub" || of[1].Delta != 0 {
+		t.Errorf("Expected Dud for Scrub to change nothing, got: %s for %s (%f)",
+			of[1].Add.FullName, of[1].Drop.FullName, of[1].Delta)
+	}
+
+	// With a bench spot open, we don't have to drop anyone.
+	fo.settings.Topology.Bench = 1
+	recommendations = fo.recommendPickups(rosters, freeAgents, 1)
+	if len(recommendations["OF"]) != 1 || recommendations["OF"][0].Drop.PlayerKey != "" {
+		t.Errorf("Expected to add without dropping, got: %v", recommendations["OF"])
+	}
+}
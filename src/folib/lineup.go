@@ -0,0 +1,226 @@
+package folib
+
+import (
+	"context"
+	"math"
+)
+
+// Picks the starting lineup which maximizes the total value of the players
+// who start.
+//
+// values[i] is the value of starting players[i].  The result maps each
+// starting position to the indices (into players) of the players starting
+// there.  Players may be eligible at several positions, and flex slots (Util,
+// P, CI, ...) may be filled by players at any of their component positions,
+// so greedily filling slots in value order doesn't work.  Instead we treat
+// this as an assignment problem between players and individual slots, and
+// solve it exactly with the Hungarian algorithm.
+func optimalLineup(players []YahooPlayer, values []float32, topology RosterTopology) map[Position][]int {
+	slots := []Position{}
+	for _, pos := range topology.positions() {
+		for i := 0; i < topology.Starters[pos]; i++ {
+			slots = append(slots, pos)
+		}
+	}
+
+	// Shift all values to be strictly positive, so that any legal
+	// assignment beats leaving a slot empty.
+	minValue := float64(0)
+	for _, v := range values {
+		minValue = math.Min(minValue, float64(v))
+	}
+
+	n := len(players)
+	if len(slots) > n {
+		n = len(slots)
+	}
+
+	weights := make([][]float64, n)
+	maxWeight := float64(0)
+	for i := range weights {
+		weights[i] = make([]float64, n)
+		if i >= len(players) {
+			continue
+		}
+		eligible := map[Position]bool{}
+		for _, pos := range topology.eligibleSlots(players[i]) {
+			eligible[pos] = true
+		}
+		for j, pos := range slots {
+			if eligible[pos] {
+				weights[i][j] = float64(values[i]) - minValue + 1
+				maxWeight = math.Max(maxWeight, weights[i][j])
+			}
+		}
+	}
+
+	// Convert to a minimum-cost problem.
+	costs := make([][]float64, n)
+	for i := range costs {
+		costs[i] = make([]float64, n)
+		for j := range costs[i] {
+			costs[i][j] = maxWeight - weights[i][j]
+		}
+	}
+
+	lineup := make(map[Position][]int)
+	for i, j := range hungarian(costs) {
+		if i < len(players) && j < len(slots) && weights[i][j] > 0 {
+			lineup[slots[j]] = append(lineup[slots[j]], i)
+		}
+	}
+	return lineup
+}
+
+// Solves the assignment problem for a square cost matrix, returning for each
+// row the column it is assigned to, such that the total cost is minimized.
+//
+// This is the O(n^3) potential-based formulation of the Hungarian algorithm.
+// Internally rows and columns are 1-indexed, with index 0 used as a sentinel.
+func hungarian(costs [][]float64) []int {
+	n := len(costs)
+	u := make([]float64, n+1)
+	v := make([]float64, n+1)
+	rowForCol := make([]int, n+1)
+	way := make([]int, n+1)
+
+	for row := 1; row <= n; row++ {
+		rowForCol[0] = row
+		col0 := 0
+		minv := make([]float64, n+1)
+		used := make([]bool, n+1)
+		for j := range minv {
+			minv[j] = math.Inf(1)
+		}
+
+		for rowForCol[col0] != 0 {
+			used[col0] = true
+			i0 := rowForCol[col0]
+			delta := math.Inf(1)
+			col1 := 0
+			for j := 1; j <= n; j++ {
+				if used[j] {
+					continue
+				}
+				cur := costs[i0-1][j-1] - u[i0] - v[j]
+				if cur < minv[j] {
+					minv[j] = cur
+					way[j] = col0
+				}
+				if minv[j] < delta {
+					delta = minv[j]
+					col1 = j
+				}
+			}
+			for j := 0; j <= n; j++ {
+				if used[j] {
+					u[rowForCol[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			col0 = col1
+		}
+
+		for col0 != 0 {
+			col1 := way[col0]
+			rowForCol[col0] = rowForCol[col1]
+			col0 = col1
+		}
+	}
+
+	assignment := make([]int, n)
+	for j := 1; j <= n; j++ {
+		if rowForCol[j] != 0 {
+			assignment[rowForCol[j]-1] = j - 1
+		}
+	}
+	return assignment
+}
+
+// Moving a player from one lineup slot to another.
+type LineupChange struct {
+	Player YahooPlayer
+	From   Position
+	To     Position
+}
+
+// Sets my lineup for a date (e.g. "2014-05-01") to the one with the best
+// projected value, among the players who are playing that day.  With
+// dryRun, nothing is changed, and we only report what would be.
+func (fo *FO) OptimizeLineup(ctx context.Context, date string, dryRun bool) ([]LineupChange, error) {
+	err := fo.loadSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	roster, err := fo.yahoo.GetLineup(ctx, fo.league.MyTeamKey, date)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := lineupChanges(roster, fo.selectDailyStarters(roster, date, fo.settings.Topology, nil))
+	if dryRun || len(changes) == 0 {
+		return changes, nil
+	}
+
+	assignments := make(map[string]Position)
+	for _, change := range changes {
+		assignments[change.Player.PlayerKey] = change.To
+	}
+	err = fo.yahoo.SetLineup(ctx, fo.league.MyTeamKey, date, assignments)
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// Where every player on the roster goes: the starters to their slots, and
+// everyone else to the bench, except players already on the IL (or in the
+// minors), who stay there.
+func lineupAssignments(roster []YahooPlayer, starters map[Position][]YahooPlayer) map[string]Position {
+	assignments := make(map[string]Position)
+	for _, player := range roster {
+		current := Position(player.SelectedPosition)
+		if current != BENCH && inactivePositions[current] {
+			assignments[player.PlayerKey] = current
+		} else {
+			assignments[player.PlayerKey] = BENCH
+		}
+	}
+	for pos, players := range starters {
+		for _, player := range players {
+			assignments[player.PlayerKey] = pos
+		}
+	}
+	return assignments
+}
+
+// What has to move to get from a roster's current lineup to the given
+// starters.  Everyone else goes to the bench, except for players already in
+// an injured or minor-league slot, who stay put.
+func lineupChanges(roster []YahooPlayer, starters map[Position][]YahooPlayer) []LineupChange {
+	target := make(map[string]Position)
+	for pos, players := range starters {
+		for _, player := range players {
+			target[player.PlayerKey] = pos
+		}
+	}
+
+	changes := []LineupChange{}
+	for _, player := range roster {
+		current := Position(player.SelectedPosition)
+		to, starting := target[player.PlayerKey]
+		if !starting {
+			if current != BENCH && inactivePositions[current] {
+				continue
+			}
+			to = BENCH
+		}
+		if to != current {
+			changes = append(changes, LineupChange{Player: player, From: current, To: to})
+		}
+	}
+	return changes
+}
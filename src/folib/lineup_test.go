@@ -0,0 +1,279 @@
+package folib
+
+import (
+	"context"
+	"testing"
+)
+
+func batter(name string, positions ...string) YahooPlayer {
+	return YahooPlayer{FullName: name, PositionType: "B", Position: positions}
+}
+
+func pitcher(name string, positions ...string) YahooPlayer {
+	return YahooPlayer{FullName: name, PositionType: "P", Position: positions}
+}
+
+func expectLineup(t *testing.T, players []YahooPlayer, lineup map[Position][]int, expected map[string]Position) {
+	actual := make(map[string]Position)
+	for pos, indices := range lineup {
+		for _, i := range indices {
+			actual[players[i].FullName] = pos
+		}
+	}
+
+	if len(actual) != len(expected) {
+		t.Errorf("Expected %d starters, got %d: %v", len(expected), len(actual), actual)
+	}
+	for name, pos := range expected {
+		if actual[name] != pos {
+			t.Errorf("Expected %s to start at '%s', but was at '%s'", name, pos, actual[name])
+		}
+	}
+}
+
+func TestMultiEligibleStarMovesToScarcePosition(t *testing.T) {
+	players := []YahooPlayer{
+		batter("Star", "1B", "C"),
+		batter("First Baseman", "1B"),
+		batter("Backup Catcher", "C"),
+	}
+	values := []float32{10, 9, 1}
+	topology := RosterTopology{Starters: map[Position]int{"C": 1, "1B": 1}}
+
+	expectLineup(t, players, optimalLineup(players, values, topology), map[string]Position{
+		"Star":          "C",
+		"First Baseman": "1B",
+	})
+}
+
+func TestUtilFilledFromLeftoverHitters(t *testing.T) {
+	players := []YahooPlayer{
+		batter("OF1", "OF"),
+		batter("OF2", "LF"),
+		batter("OF3", "CF"),
+		batter("SS1", "SS"),
+	}
+	values := []float32{4, 3, 2, 1}
+	topology := RosterTopology{Starters: map[Position]int{"OF": 2, "Util": 1, "SS": 1}}
+
+	expectLineup(t, players, optimalLineup(players, values, topology), map[string]Position{
+		"OF1": "OF",
+		"OF2": "OF",
+		"OF3": "Util",
+		"SS1": "SS",
+	})
+}
+
+func TestUtilDoesNotTakeStarFromOnlyEligibleSlot(t *testing.T) {
+	players := []YahooPlayer{
+		batter("Star", "SS", "Util"),
+		batter("Slugger", "1B", "Util"),
+		batter("Scrub", "SS", "Util"),
+	}
+	values := []float32{10, 8, 1}
+	topology := RosterTopology{Starters: map[Position]int{"Util": 1, "SS": 1}}
+
+	expectLineup(t, players, optimalLineup(players, values, topology), map[string]Position{
+		"Star":    "SS",
+		"Slugger": "Util",
+	})
+}
+
+func TestPitcherFlexSlot(t *testing.T) {
+	players := []YahooPlayer{
+		pitcher("Ace", "SP"),
+		pitcher("Number Two", "SP"),
+		pitcher("Closer", "RP"),
+		pitcher("Setup", "RP"),
+		pitcher("Swingman", "SP", "RP"),
+	}
+	values := []float32{10, 8, 7, 2, 5}
+	topology := RosterTopology{Starters: map[Position]int{"SP": 2, "RP": 1, "P": 1}}
+
+	expectLineup(t, players, optimalLineup(players, values, topology), map[string]Position{
+		"Ace":        "SP",
+		"Number Two": "SP",
+		"Closer":     "RP",
+		"Swingman":   "P",
+	})
+}
+
+func TestHittersCantFillPitchingSlots(t *testing.T) {
+	players := []YahooPlayer{
+		batter("Slugger", "1B", "Util"),
+		pitcher("Ace", "SP", "P"),
+	}
+	values := []float32{10, 1}
+	topology := RosterTopology{Starters: map[Position]int{"P": 2}}
+
+	expectLineup(t, players, optimalLineup(players, values, topology), map[string]Position{
+		"Ace": "P",
+	})
+}
+
+func TestInjuredPlayersDontStart(t *testing.T) {
+	players := []YahooPlayer{
+		batter("Hurt", "C", "IL"),
+		batter("Healthy", "C"),
+	}
+	values := []float32{10, 1}
+	topology := RosterTopology{Starters: map[Position]int{"C": 1}, Injured: 1}
+
+	expectLineup(t, players, optimalLineup(players, values, topology), map[string]Position{
+		"Healthy": "C",
+	})
+}
+
+func TestNegativeValuesStillFillSlots(t *testing.T) {
+	players := []YahooPlayer{
+		batter("Bad", "C"),
+		batter("Worse", "1B"),
+	}
+	values := []float32{-1, -5}
+	topology := RosterTopology{Starters: map[Position]int{"C": 1, "1B": 1, "Util": 1}}
+
+	expectLineup(t, players, optimalLineup(players, values, topology), map[string]Position{
+		"Bad":   "C",
+		"Worse": "1B",
+	})
+}
+
+func TestHungarian(t *testing.T) {
+	costs := [][]float64{
+		{4, 1, 3},
+		{2, 0, 5},
+		{3, 2, 2},
+	}
+	assignment := hungarian(costs)
+	total := float64(0)
+	for i, j := range assignment {
+		total += costs[i][j]
+	}
+	if total != 5 {
+		t.Errorf("Expected minimum cost of 5, got %f (%v)", total, assignment)
+	}
+}
+
+const lineupRosterXml = `<?xml version="1.0" encoding="UTF-8"?>
+<fantasy_content>
+  <team>
+    <team_key>328.l.1.t.1</team_key>
+    <roster>
+      <players>
+        <player>
+          <player_key>328.p.1</player_key>
+          <name><full>Slugger</full></name>
+          <position_type>B</position_type>
+          <eligible_positions><position>OF</position></eligible_positions>
+          <selected_position><date>2014-05-01</date><position>BN</position></selected_position>
+        </player>
+        <player>
+          <player_key>328.p.2</player_key>
+          <name><full>Scrub</full></name>
+          <position_type>B</position_type>
+          <eligible_positions><position>OF</position></eligible_positions>
+          <selected_position><date>2014-05-01</date><position>OF</position></selected_position>
+        </player>
+        <player>
+          <player_key>328.p.3</player_key>
+          <name><full>Hurt</full></name>
+          <position_type>B</position_type>
+          <eligible_positions><position>OF</position><position>IL</position></eligible_positions>
+          <selected_position><date>2014-05-01</date><position>IL</position></selected_position>
+        </player>
+      </players>
+    </roster>
+  </team>
+</fantasy_content>`
+
+const expectedLineupXml = `<?xml version="1.0" encoding="UTF-8"?>
+<fantasy_content>
+  <roster>
+    <coverage_type>date</coverage_type>
+    <date>2014-05-01</date>
+    <players>
+      <player>
+        <player_key>328.p.1</player_key>
+        <position>OF</position>
+      </player>
+      <player>
+        <player_key>328.p.2</player_key>
+        <position>BN</position>
+      </player>
+    </players>
+  </roster>
+</fantasy_content>`
+
+func TestLineupXml(t *testing.T) {
+	body, err := lineupXml("2014-05-01", map[string]Position{"328.p.2": BENCH, "328.p.1": "OF"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != expectedLineupXml {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expectedLineupXml, body)
+	}
+}
+
+func TestLineupChanges(t *testing.T) {
+	starting := YahooPlayer{PlayerKey: "1", SelectedPosition: "BN"}
+	benched := YahooPlayer{PlayerKey: "2", SelectedPosition: "C"}
+	unchanged := YahooPlayer{PlayerKey: "3", SelectedPosition: "1B"}
+	injured := YahooPlayer{PlayerKey: "4", SelectedPosition: "IL"}
+	bench := YahooPlayer{PlayerKey: "5", SelectedPosition: "BN"}
+
+	changes := lineupChanges(
+		[]YahooPlayer{starting, benched, unchanged, injured, bench},
+		map[Position][]YahooPlayer{"C": {starting}, "1B": {unchanged}})
+
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 changes, got: %v", changes)
+	}
+	if changes[0].Player.PlayerKey != "1" || changes[0].From != BENCH || changes[0].To != "C" {
+		t.Errorf("Expected player 1 to move from BN to C, got: %v", changes[0])
+	}
+	if changes[1].Player.PlayerKey != "2" || changes[1].From != "C" || changes[1].To != BENCH {
+		t.Errorf("Expected player 2 to move from C to BN, got: %v", changes[1])
+	}
+}
+
+func TestOptimizeLineup(t *testing.T) {
+	yahoo, client := newFakeYahoo(t)
+	yahoo.respond("/team/328.l.1.t.1/roster;date=2014-05-01", lineupRosterXml)
+
+	fo := testFO(fakeStatsClient{
+		"Slugger": {B_HOME_RUNS: 40},
+		"Scrub":   {B_HOME_RUNS: 1},
+		"Hurt":    {B_HOME_RUNS: 50},
+	}, &LeagueSettings{
+		Categories: ScoringCategories{B_HOME_RUNS: HIGHER_IS_BETTER},
+		Topology:   RosterTopology{Starters: map[Position]int{"OF": 1}, Bench: 1, Injured: 1},
+	})
+	fo.yahoo = client
+	fo.league = &LeagueContext{LeagueKey: "328.l.1", MyTeamKey: "328.l.1.t.1", MyTeamID: 1}
+
+	changes, err := fo.OptimizeLineup(context.Background(), "2014-05-01", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Player.FullName != "Slugger" || changes[1].Player.FullName != "Scrub" {
+		t.Errorf("Expected to swap Slugger and Scrub, got: %v", changes)
+	}
+	if len(yahoo.writes()) != 0 {
+		t.Errorf("A dry run shouldn't change anything, got: %v", yahoo.writes())
+	}
+
+	_, err = fo.OptimizeLineup(context.Background(), "2014-05-01", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writes := yahoo.writes()
+	if len(writes) != 1 {
+		t.Fatalf("Expected one write, got: %v", writes)
+	}
+	if writes[0].Method != "PUT" || writes[0].Path != "/team/328.l.1.t.1/roster" {
+		t.Errorf("Expected a PUT to the roster, got: %s %s", writes[0].Method, writes[0].Path)
+	}
+	if writes[0].Body != expectedLineupXml {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expectedLineupXml, writes[0].Body)
+	}
+}
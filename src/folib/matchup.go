@@ -0,0 +1,254 @@
+package folib
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// Roughly how long the MLB regular season is, for when we don't know the
+	// league's dates.
+	SEASON_DAYS = 183
+)
+
+// How one category of a head-to-head matchup is likely to turn out.
+type CategoryOutlook struct {
+	Stat           StatID
+	Order          SortOrder
+	Mine           Stat // Projected end-of-week value
+	Theirs         Stat
+	WinProbability float64
+	TieProbability float64
+}
+
+type MatchupProjection struct {
+	Week       int
+	Opponent   string
+	Categories []CategoryOutlook
+
+	// The expected category record, e.g. 6.2-3.5-0.3.
+	ExpectedWins   float64
+	ExpectedLosses float64
+	ExpectedTies   float64
+}
+
+// Projects this week's head-to-head matchup (or a given week's, if week is
+// non-zero) by adding the rest-of-week projections for each team's starters
+// to what they've done so far this week.
+func (fo *FO) ProjectMatchup(ctx context.Context, week int) (*MatchupProjection, error) {
+	lines, err := fo.matchupLines(ctx, week, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	projection := projectMatchup(
+		lines.mineActual, lines.mineRest, lines.theirsActual, lines.theirsRest, fo.settings.Categories)
+	projection.Week = lines.matchup.Week
+	projection.Opponent = lines.opponent
+	return projection, nil
+}
+
+// What each side of a matchup has done so far, and is projected to do over
+// the rest of it.
+type matchupLines struct {
+	matchup  *YahooMatchup
+	opponent string
+	mine     []YahooPlayer // My roster
+	// The days left to play, as of when the lines were projected.
+	start, end time.Time
+
+	mineActual, mineRest     StatLine
+	theirsActual, theirsRest StatLine
+}
+
+func (fo *FO) matchupLines(ctx context.Context, week int, now time.Time) (*matchupLines, error) {
+	err := fo.loadSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scoreboard, err := fo.yahoo.GetScoreboard(ctx, fo.league.LeagueKey, week)
+	if err != nil {
+		return nil, err
+	}
+
+	matchup, mine, theirs, err := findMatchup(scoreboard, fo.league.MyTeamKey)
+	if err != nil {
+		return nil, err
+	}
+
+	rosters, err := fo.yahoo.LeagueRosters(ctx, fo.league.LeagueKey)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := &matchupLines{matchup: matchup, opponent: theirs.Name, mine: (*rosters)[mine.TeamId]}
+	lines.mineActual, err = parseTeamStats(mine.Stats)
+	if err != nil {
+		return nil, err
+	}
+	lines.theirsActual, err = parseTeamStats(theirs.Stats)
+	if err != nil {
+		return nil, err
+	}
+
+	lines.start, lines.end, err = remainingMatchupDays(matchup, now)
+	if err != nil {
+		return nil, err
+	}
+
+	lines.mineRest = fo.projectStartersBetween((*rosters)[mine.TeamId], lines.start, lines.end)
+	lines.theirsRest = fo.projectStartersBetween((*rosters)[theirs.TeamId], lines.start, lines.end)
+	return lines, nil
+}
+
+// Projects the combined stats of a roster's starters, over the given
+// fraction of a season.
+func (fo *FO) projectStarters(roster []YahooPlayer, seasonFraction float64) StatLine {
+	return merge(fo.projectStarterLines(roster, seasonFraction))
+}
+
+// Same as projectStarters, but keeps each starter's projection separate.
+func (fo *FO) projectStarterLines(roster []YahooPlayer, seasonFraction float64) []StatLine {
+	lines := []StatLine{}
+	for _, players := range fo.selectStarters(roster, fo.settings.Topology) {
+		for _, player := range players {
+			line := fo.projections.GetStatLine(fo.playerID(player))
+			lines = append(lines, scaleStatLine(line, seasonFraction))
+		}
+	}
+	return lines
+}
+
+func findMatchup(scoreboard *YahooScoreboard, teamKey string) (*YahooMatchup, *YahooTeam, *YahooTeam, error) {
+	for i := range scoreboard.Matchups {
+		matchup := &scoreboard.Matchups[i]
+		if len(matchup.Teams) != 2 {
+			continue
+		}
+		if matchup.Teams[0].TeamKey == teamKey {
+			return matchup, &matchup.Teams[0], &matchup.Teams[1], nil
+		}
+		if matchup.Teams[1].TeamKey == teamKey {
+			return matchup, &matchup.Teams[1], &matchup.Teams[0], nil
+		}
+	}
+	return nil, nil, nil, fmt.Errorf("No matchup for team %s in week %d", teamKey, scoreboard.Week)
+}
+
+// The days left to play in this matchup, counting today.  If there are none,
+// start is after end.
+func remainingMatchupDays(matchup *YahooMatchup, now time.Time) (start, end time.Time, err error) {
+	start, err = time.Parse("2006-01-02", matchup.WeekStart)
+	if err != nil {
+		return start, end, err
+	}
+	end, err = time.Parse("2006-01-02", matchup.WeekEnd)
+	if err != nil {
+		return start, end, err
+	}
+	if matchup.Status == "postevent" {
+		return end.Add(ONE_DAY), end, nil
+	}
+
+	today, err := time.Parse("2006-01-02", now.Format("2006-01-02"))
+	if err != nil {
+		return start, end, err
+	}
+	if today.After(start) {
+		start = today
+	}
+	return start, end, nil
+}
+
+func projectMatchup(mineActual, mineRest, theirsActual, theirsRest StatLine, categories ScoringCategories) *MatchupProjection {
+	mine := merge([]StatLine{mineActual, mineRest})
+	theirs := merge([]StatLine{theirsActual, theirsRest})
+
+	stats := make([]StatID, 0, len(categories))
+	for stat := range categories {
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i] < stats[j] })
+
+	projection := &MatchupProjection{}
+	for _, stat := range stats {
+		variance := remainingVariance(stat, mine, mineRest) + remainingVariance(stat, theirs, theirsRest)
+		win, tie := winProbability(mine[stat], theirs[stat], variance, categories[stat], !isRateStat(stat))
+
+		projection.Categories = append(projection.Categories, CategoryOutlook{
+			Stat:           stat,
+			Order:          categories[stat],
+			Mine:           mine[stat],
+			Theirs:         theirs[stat],
+			WinProbability: win,
+			TieProbability: tie,
+		})
+		projection.ExpectedWins += win
+		projection.ExpectedTies += tie
+		projection.ExpectedLosses += 1 - win - tie
+	}
+	return projection
+}
+
+// The variance in a team's final total for a stat, due to the games it has
+// left to play.  Counting stats are treated as Poisson, so their variance is
+// the expected remaining amount.  For rate stats, only the numerator is
+// treated as random, and is spread over the team's projected final
+// denominator (e.g. at bats).
+func remainingVariance(stat StatID, total, rest StatLine) float64 {
+	if !isRateStat(stat) {
+		return math.Max(float64(rest[stat]), 0)
+	}
+
+	formula := rateFormulaFor(stat)
+	if formula == nil {
+		return 0
+	}
+	denominator, ok := formula.denominatorOf(total)
+	if !ok || denominator <= 0 {
+		return 0
+	}
+
+	// Each numerator term is Poisson, so a weighted sum of them has variance
+	// sum(weight^2 * mean).
+	numeratorVariance := float64(0)
+	for _, term := range formula.numerator {
+		numeratorVariance += float64(term.weight*term.weight) * math.Max(float64(rest[term.stat]), 0)
+	}
+	scale := float64(formula.scale)
+	d := float64(denominator)
+	return scale * scale * numeratorVariance / (d * d)
+}
+
+// The chance that a normally distributed difference between two teams comes
+// out in our favor, or even.  Counting stats only come in whole numbers, so
+// for them a difference within half of zero is a tie.
+func winProbability(mine, theirs Stat, variance float64, order SortOrder, counting bool) (win, tie float64) {
+	diff := float64(mine - theirs)
+	if order == LOWER_IS_BETTER {
+		diff = -diff
+	}
+
+	if variance <= 0 {
+		switch {
+		case diff > 0:
+			return 1, 0
+		case diff < 0:
+			return 0, 0
+		default:
+			return 0, 1
+		}
+	}
+
+	spread := math.Sqrt(2 * variance)
+	if !counting {
+		return 0.5 * (1 + math.Erf(diff/spread)), 0
+	}
+	win = 0.5 * (1 + math.Erf((diff-0.5)/spread))
+	loss := 0.5 * (1 - math.Erf((diff+0.5)/spread))
+	return win, 1 - win - loss
+}
@@ -0,0 +1,77 @@
+package folib
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWinProbability(t *testing.T) {
+	if win, tie := winProbability(10, 10, 4, HIGHER_IS_BETTER, false); win != 0.5 || tie != 0 {
+		t.Errorf("Even matchup should be 50%%, got: %f (tie %f)", win, tie)
+	}
+	if win, _ := winProbability(12, 10, 4, HIGHER_IS_BETTER, false); math.Abs(win-0.8413) > 0.001 {
+		t.Errorf("One standard deviation ahead should be ~84%%, got: %f", win)
+	}
+	if win, _ := winProbability(3.00, 4.00, 0.25, LOWER_IS_BETTER, false); math.Abs(win-0.9772) > 0.001 {
+		t.Errorf("Lower ERA by two standard deviations should be ~98%%, got: %f", win)
+	}
+	if win, tie := winProbability(5, 5, 0, HIGHER_IS_BETTER, true); win != 0 || tie != 1 {
+		t.Errorf("Finished, equal category should be a tie, got: %f (tie %f)", win, tie)
+	}
+	if win, _ := winProbability(6, 5, 0, HIGHER_IS_BETTER, true); win != 1 {
+		t.Errorf("Finished, winning category should be a win, got: %f", win)
+	}
+
+	// Counting stats tie whenever they come out within half of each other.
+	win, tie := winProbability(5, 5, 4, HIGHER_IS_BETTER, true)
+	if math.Abs(win-0.4013) > 0.001 || math.Abs(tie-0.1974) > 0.001 {
+		t.Errorf("Even saves should tie ~20%% of the time, got: %f (tie %f)", win, tie)
+	}
+	if win, tie := winProbability(7, 5, 4, HIGHER_IS_BETTER, true); math.Abs(win-0.7734) > 0.001 || math.Abs(tie-0.1210) > 0.001 {
+		t.Errorf("Two saves ahead should win ~77%% of the time, got: %f (tie %f)", win, tie)
+	}
+}
+
+func TestRemainingMatchupDays(t *testing.T) {
+	matchup := &YahooMatchup{WeekStart: "2014-04-28", WeekEnd: "2014-05-04", Status: "midevent"}
+	expectDays := func(now time.Time, expected int) {
+		start, end, err := remainingMatchupDays(matchup, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if days := int(end.Sub(start)/ONE_DAY) + 1; days != expected {
+			t.Errorf("On %s, expected %d days left, got: %d", now.Format("2006-01-02"), expected, days)
+		}
+	}
+
+	expectDays(time.Date(2014, 5, 2, 15, 0, 0, 0, time.UTC), 3)
+	// The whole week.
+	expectDays(time.Date(2014, 4, 20, 15, 0, 0, 0, time.UTC), 7)
+
+	matchup.Status = "postevent"
+	expectDays(time.Date(2014, 5, 2, 15, 0, 0, 0, time.UTC), 0)
+}
+
+func TestProjectMatchup(t *testing.T) {
+	categories := ScoringCategories{
+		B_HOME_RUNS:    HIGHER_IS_BETTER,
+		B_STOLEN_BASES: HIGHER_IS_BETTER,
+		P_WINS:         HIGHER_IS_BETTER,
+	}
+
+	projection := projectMatchup(
+		StatLine{B_HOME_RUNS: 10, B_STOLEN_BASES: 2, P_WINS: 3},
+		StatLine{},
+		StatLine{B_HOME_RUNS: 5, B_STOLEN_BASES: 2, P_WINS: 4},
+		StatLine{},
+		categories)
+
+	if projection.ExpectedWins != 1 || projection.ExpectedTies != 1 || projection.ExpectedLosses != 1 {
+		t.Errorf("Expected a 1-1-1 record, got %0.1f-%0.1f-%0.1f",
+			projection.ExpectedWins, projection.ExpectedLosses, projection.ExpectedTies)
+	}
+	if len(projection.Categories) != 3 {
+		t.Errorf("Expected 3 categories, got: %v", projection.Categories)
+	}
+}
@@ -30,6 +30,8 @@ const (
 	B_TRIPLES         StatID = 15
 	B_WALKS           StatID = 16
 	B_SINGLES         StatID = 17
+	B_HIT_BY_PITCH    StatID = 18
+	B_SACRIFICE_FLIES StatID = 19
 
 	P_EARNED_RUNS        StatID = 1001
 	P_EARNED_RUN_AVERAGE StatID = 1002
@@ -47,46 +49,198 @@ const (
 	P_WINS               StatID = 1014
 	P_BATTERS_FACED      StatID = 1015
 	P_SAVE_CHANCES       StatID = 1016
+	P_QUALITY_STARTS     StatID = 1017
+	P_STRIKEOUTS_PER_9   StatID = 1018
 )
 
-func isRateStat(s StatID) bool {
-	return s == B_BATTING_AVG ||
-		s == B_ON_BASE_PCT ||
-		s == B_SLUGGING ||
-		s == P_EARNED_RUN_AVERAGE ||
-		s == P_WHIP
+type SortOrder int
+
+const (
+	HIGHER_IS_BETTER SortOrder = iota
+	LOWER_IS_BETTER
+)
+
+// The stats a league is scored on, along with which direction is better for
+// each of them.
+type ScoringCategories map[StatID]SortOrder
+
+// One term in the sum that makes up a rate stat's numerator or denominator.
+type statTerm struct {
+	stat   StatID
+	weight Stat
+	// Optional terms are treated as zero when missing, since not every
+	// source reports them.
+	optional bool
+}
+
+// How a rate stat is computed from counting stats, i.e.
+// scale * sum(numerator) / sum(denominator).
+type rateFormula struct {
+	numerator   []statTerm
+	denominator []statTerm
+	scale       Stat
+}
+
+func sumTerms(terms []statTerm, line StatLine) (Stat, bool) {
+	total := Stat(0)
+	for _, term := range terms {
+		v, ok := line[term.stat]
+		if !ok && !term.optional {
+			return 0, false
+		}
+		total += term.weight * v
+	}
+	return total, true
+}
+
+// The numerator of the formula for this line, if the line has everything
+// needed to compute it.
+func (f rateFormula) numeratorOf(line StatLine) (Stat, bool) {
+	return sumTerms(f.numerator, line)
 }
 
-func lowerIsBetter(s StatID) bool {
-	return s == P_EARNED_RUN_AVERAGE ||
-		s == P_WHIP
+// The denominator of the formula for this line, if the line has everything
+// needed to compute it.
+func (f rateFormula) denominatorOf(line StatLine) (Stat, bool) {
+	return sumTerms(f.denominator, line)
 }
 
+// Combines several stat lines (e.g. all the starters on a team) into one.
+// Counting stats are added up, and rate stats are recomputed from the
+// combined counting stats they're made of, so that (for example) a 200 inning
+// starter counts for more of a team's ERA than a 60 inning reliever.
 func merge(indiv []StatLine) StatLine {
-	// replace equal-weight with unrolled/counting stats merge
 	totals := make(StatLine)
-
-	rawTotals := make(StatLine)
-	counts := make(StatLine)
+	rates := make(map[StatID]bool)
 
 	for i := range indiv {
-		for s := range indiv[i] {
-			rawTotals[s] += indiv[i][s]
-			if indiv[i][s] > 0.01 {
-				counts[s] += 1
+		for s, v := range indiv[i] {
+			if isRateStat(s) {
+				rates[s] = true
+			} else {
+				totals[s] += v
+			}
+		}
+	}
+
+	for s := range rates {
+		totals[s] = mergeRate(s, indiv)
+	}
+
+	return totals
+}
+
+func mergeRate(s StatID, indiv []StatLine) Stat {
+	if formula := rateFormulaFor(s); formula != nil {
+		numerator, denominator := Stat(0), Stat(0)
+		known, rateOnly := []StatLine{}, []StatLine{}
+		for _, line := range indiv {
+			d, hasDenominator := formula.denominatorOf(line)
+			if !hasDenominator {
+				if _, hasRate := line[s]; hasRate {
+					rateOnly = append(rateOnly, line)
+				}
+				continue
+			}
+			if n, hasNumerator := formula.numeratorOf(line); hasNumerator {
+				numerator += n
+				denominator += d
+				known = append(known, line)
+			} else if rate, hasRate := line[s]; hasRate {
+				// Without the components, weight the line's rate by its
+				// denominator instead.
+				numerator += rate * d / formula.scale
+				denominator += d
+				known = append(known, line)
 			}
 		}
+		for _, line := range rateOnly {
+			if d, ok := estimateDenominator(s, formula, line, known); ok {
+				numerator += line[s] * d / formula.scale
+				denominator += d
+			}
+		}
+		if denominator > 0 {
+			return formula.scale * numerator / denominator
+		}
+	}
+
+	// We have no idea how much playing time is behind each line, so all we
+	// can do is weight them equally.
+	total, count := Stat(0), Stat(0)
+	for _, line := range indiv {
+		if v := line[s]; v > 0.01 {
+			total += v
+			count += 1
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return total / count
+}
+
+// The playing time stats (see playingTimeStats) to estimate denominators
+// from, most telling first.
+var denominatorProxies = []StatID{
+	B_PLATE_APPS, B_AT_BATS, B_GAMES,
+	P_INNINGS, P_BATTERS_FACED, P_GAMES,
+}
+
+// Guesses the denominator behind a line which only has the rate, e.g. a
+// Yahoo team line with OBP but not walks, from the lines whose denominators
+// we know.  If the line has a playing time stat they have too, its
+// denominator is taken to be in the same proportion to it as theirs are.
+// Otherwise, it's weighted like the average of them.
+func estimateDenominator(s StatID, formula *rateFormula, line StatLine, known []StatLine) (Stat, bool) {
+	if len(known) == 0 {
+		return 0, false
 	}
+	side := statInfoById[s].Side
 
-	for s := range rawTotals {
+	for _, pt := range denominatorProxies {
+		playingTime, ok := line[pt]
+		if !ok || playingTime <= 0 || statInfoById[pt].Side != side {
+			continue
+		}
+		denominator, units := Stat(0), Stat(0)
+		for _, other := range known {
+			if v := other[pt]; v > 0 {
+				d, _ := formula.denominatorOf(other)
+				denominator += d
+				units += v
+			}
+		}
+		if units > 0 {
+			return playingTime * denominator / units, true
+		}
+	}
+
+	// As with no playing time at all, a rate of zero most likely means
+	// there isn't one yet, e.g. ERA before any innings.
+	if line[s] <= 0.01 {
+		return 0, false
+	}
+	total := Stat(0)
+	for _, other := range known {
+		d, _ := formula.denominatorOf(other)
+		total += d
+	}
+	return total / Stat(len(known)), true
+}
+
+// Scales the counting stats in a line by the given factor, e.g. to project
+// part of a season.  Rate stats are unaffected.
+func scaleStatLine(line StatLine, factor float64) StatLine {
+	scaled := make(StatLine)
+	for s, v := range line {
 		if isRateStat(s) {
-			totals[s] = rawTotals[s] / counts[s]
+			scaled[s] = v
 		} else {
-			totals[s] = rawTotals[s]
+			scaled[s] = v * Stat(factor)
 		}
 	}
-
-	return totals
+	return scaled
 }
 
 type StatsClient interface {
@@ -0,0 +1,151 @@
+package folib
+
+import (
+	"math"
+	"testing"
+)
+
+func closeEnough(a, b Stat) bool {
+	return math.Abs(float64(a-b)) < 0.0005
+}
+
+// Real 2013 lines, checking that each formula reproduces the published rate
+// from its components.
+func TestRateFormulas(t *testing.T) {
+	cases := []struct {
+		name     string
+		stat     StatID
+		line     StatLine
+		expected Stat
+	}{
+		{"AVG", B_BATTING_AVG,
+			StatLine{B_HITS: 193, B_AT_BATS: 555}, .348},
+		{"OBP without HBP/SF", B_ON_BASE_PCT,
+			StatLine{B_HITS: 193, B_WALKS: 90, B_AT_BATS: 555}, .439},
+		{"OBP", B_ON_BASE_PCT,
+			StatLine{B_HITS: 193, B_WALKS: 90, B_HIT_BY_PITCH: 5, B_AT_BATS: 555, B_SACRIFICE_FLIES: 2}, .442},
+		{"SLG", B_SLUGGING,
+			StatLine{B_HITS: 193, B_DOUBLES: 26, B_TRIPLES: 1, B_HOME_RUNS: 44, B_AT_BATS: 555}, .636},
+		{"ERA", P_EARNED_RUN_AVERAGE,
+			StatLine{P_EARNED_RUNS: 48, P_INNINGS: 236}, 1.83},
+		{"WHIP", P_WHIP,
+			StatLine{P_WALKS: 52, P_HITS: 164, P_INNINGS: 236}, 0.915},
+		{"K/9", P_STRIKEOUTS_PER_9,
+			StatLine{P_STRIKE_OUTS: 232, P_INNINGS: 236}, 8.847},
+	}
+
+	for _, c := range cases {
+		formula := rateFormulaFor(c.stat)
+		if formula == nil {
+			t.Errorf("%s: no formula", c.name)
+			continue
+		}
+		n, ok := formula.numeratorOf(c.line)
+		if !ok {
+			t.Errorf("%s: couldn't compute numerator from %v", c.name, c.line)
+			continue
+		}
+		d, ok := formula.denominatorOf(c.line)
+		if !ok {
+			t.Errorf("%s: couldn't compute denominator from %v", c.name, c.line)
+			continue
+		}
+		actual := formula.scale * n / d
+		if math.Abs(float64(actual-c.expected)) > 0.002 {
+			t.Errorf("%s: expected %f, got %f", c.name, c.expected, actual)
+		}
+	}
+
+	for _, stat := range []StatID{B_BATTING_AVG, B_ON_BASE_PCT, B_SLUGGING, P_EARNED_RUN_AVERAGE, P_WHIP, P_STRIKEOUTS_PER_9} {
+		if !isRateStat(stat) {
+			t.Errorf("Stat %d should be a rate stat", stat)
+		}
+	}
+}
+
+func TestMergeCountingStats(t *testing.T) {
+	merged := merge([]StatLine{
+		StatLine{B_HOME_RUNS: 30, B_RUNS: 100},
+		StatLine{B_HOME_RUNS: 10},
+	})
+
+	if merged[B_HOME_RUNS] != 40 || merged[B_RUNS] != 100 {
+		t.Errorf("Wrong totals: %v", merged)
+	}
+}
+
+func TestMergeRateStatsFromComponents(t *testing.T) {
+	ace := StatLine{P_EARNED_RUNS: 50, P_INNINGS: 200, P_EARNED_RUN_AVERAGE: 2.25}
+	reliever := StatLine{P_EARNED_RUNS: 40, P_INNINGS: 60, P_EARNED_RUN_AVERAGE: 6.00}
+
+	merged := merge([]StatLine{ace, reliever})
+
+	// 90 ER in 260 IP, not the 4.125 you'd get by averaging the ERAs.
+	if !closeEnough(merged[P_EARNED_RUN_AVERAGE], 3.115) {
+		t.Errorf("Expected ERA of 3.115, got %f", merged[P_EARNED_RUN_AVERAGE])
+	}
+	if merged[P_INNINGS] != 260 {
+		t.Errorf("Expected 260 IP, got %f", merged[P_INNINGS])
+	}
+}
+
+func TestMergeRateStatsWeightsByDenominator(t *testing.T) {
+	// No hits, just averages and at bats.
+	merged := merge([]StatLine{
+		StatLine{B_BATTING_AVG: .300, B_AT_BATS: 600},
+		StatLine{B_BATTING_AVG: .200, B_AT_BATS: 200},
+	})
+
+	if !closeEnough(merged[B_BATTING_AVG], .275) {
+		t.Errorf("Expected AVG of .275, got %f", merged[B_BATTING_AVG])
+	}
+}
+
+func TestMergeRateStatsMixingComponentsAndRates(t *testing.T) {
+	merged := merge([]StatLine{
+		StatLine{P_WALKS: 40, P_HITS: 160, P_INNINGS: 200},
+		StatLine{P_WHIP: 1.50, P_INNINGS: 50},
+	})
+
+	// (200 + 75) / 250
+	if !closeEnough(merged[P_WHIP], 1.10) {
+		t.Errorf("Expected WHIP of 1.10, got %f", merged[P_WHIP])
+	}
+}
+
+func TestMergeRateOnlyLineWithComponents(t *testing.T) {
+	// What Yahoo has for a team so far (AB from H/AB, but no walks), and a
+	// projection for the rest of the season.
+	actual := StatLine{B_ON_BASE_PCT: .400, B_HITS: 100, B_AT_BATS: 300}
+	rest := StatLine{B_ON_BASE_PCT: .300, B_HITS: 70, B_WALKS: 20, B_AT_BATS: 280}
+
+	// The projection has 300 PA per 280 AB, so about 321.4 PA so far:
+	// (.400 * 321.4 + 90) / (321.4 + 300)
+	merged := merge([]StatLine{actual, rest})
+	if !closeEnough(merged[B_ON_BASE_PCT], .3517) {
+		t.Errorf("Expected OBP of .3517, got %f", merged[B_ON_BASE_PCT])
+	}
+
+	// Without anything to go on, both lines count the same.
+	merged = merge([]StatLine{{B_ON_BASE_PCT: .400, B_HITS: 80}, rest})
+	if !closeEnough(merged[B_ON_BASE_PCT], .350) {
+		t.Errorf("Expected OBP of .350, got %f", merged[B_ON_BASE_PCT])
+	}
+
+	merged = merge([]StatLine{{P_EARNED_RUN_AVERAGE: 4.50, P_INNINGS: 100}, {P_EARNED_RUNS: 60, P_INNINGS: 200}})
+	if !closeEnough(merged[P_EARNED_RUN_AVERAGE], 3.30) {
+		t.Errorf("Expected ERA of 3.30, got %f", merged[P_EARNED_RUN_AVERAGE])
+	}
+}
+
+func TestMergeRateStatsWithoutPlayingTime(t *testing.T) {
+	merged := merge([]StatLine{
+		StatLine{P_EARNED_RUN_AVERAGE: 3.00},
+		StatLine{P_EARNED_RUN_AVERAGE: 5.00},
+		StatLine{P_EARNED_RUN_AVERAGE: 0},
+	})
+
+	if !closeEnough(merged[P_EARNED_RUN_AVERAGE], 4.00) {
+		t.Errorf("Expected ERA of 4.00, got %f", merged[P_EARNED_RUN_AVERAGE])
+	}
+}
@@ -0,0 +1,322 @@
+package folib
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Stats which measure playing time, rather than performance.  We take these
+// as given when sampling a player's season, and only vary what they do with
+// it.
+var playingTimeStats = map[StatID]bool{
+	B_AT_BATS:       true,
+	B_GAMES:         true,
+	B_PLATE_APPS:    true,
+	P_BATTERS_FACED: true,
+	P_GAMES:         true,
+	P_INNINGS:       true,
+	P_SAVE_CHANCES:  true,
+	P_STARTS:        true,
+}
+
+// Stats which count successes out of a number of chances, mapped to the stat
+// that counts the chances.  These are sampled as binomials; everything else
+// is Poisson.
+var binomialStats = map[StatID]StatID{
+	B_HITS:           B_AT_BATS,
+	P_QUALITY_STARTS: P_STARTS,
+	P_SAVES:          P_SAVE_CHANCES,
+}
+
+// One way a player's projected stats might actually turn out.  Rate stats are
+// recomputed from the sampled components where we have them, and are
+// otherwise left as projected.
+func sampleStatLine(line StatLine, rng *rand.Rand) StatLine {
+	sampled := make(StatLine)
+	for stat, value := range line {
+		if isRateStat(stat) {
+			continue
+		}
+		if playingTimeStats[stat] {
+			sampled[stat] = value
+			continue
+		}
+		if chances, ok := binomialStats[stat]; ok && line[chances] >= value && line[chances] > 0 {
+			n := int(math.Floor(float64(line[chances]) + 0.5))
+			sampled[stat] = Stat(sampleBinomial(n, float64(value/line[chances]), rng))
+			continue
+		}
+		sampled[stat] = Stat(samplePoisson(float64(value), rng))
+	}
+
+	for stat, value := range line {
+		if !isRateStat(stat) {
+			continue
+		}
+		if rate, ok := rateFromComponents(stat, sampled); ok {
+			sampled[stat] = rate
+		} else {
+			sampled[stat] = value
+		}
+	}
+	return sampled
+}
+
+// Above this, Poisson and binomial samples are drawn from the normal
+// approximation, rather than counting out individual events.
+const EXACT_SAMPLE_LIMIT = 30
+
+func samplePoisson(mean float64, rng *rand.Rand) float64 {
+	if mean <= 0 {
+		return 0
+	}
+	if mean > EXACT_SAMPLE_LIMIT {
+		return math.Max(0, math.Floor(mean+math.Sqrt(mean)*rng.NormFloat64()+0.5))
+	}
+
+	// Knuth's algorithm.
+	limit := math.Exp(-mean)
+	k, p := 0.0, rng.Float64()
+	for p > limit {
+		k++
+		p *= rng.Float64()
+	}
+	return k
+}
+
+func sampleBinomial(n int, p float64, rng *rand.Rand) float64 {
+	if n <= 0 || p <= 0 {
+		return 0
+	}
+	if p >= 1 {
+		return float64(n)
+	}
+	mean, variance := float64(n)*p, float64(n)*p*(1-p)
+	if variance > EXACT_SAMPLE_LIMIT {
+		x := math.Floor(mean + math.Sqrt(variance)*rng.NormFloat64() + 0.5)
+		return math.Min(math.Max(x, 0), float64(n))
+	}
+
+	k := 0.0
+	for i := 0; i < n; i++ {
+		if rng.Float64() < p {
+			k++
+		}
+	}
+	return k
+}
+
+// How a team's season might turn out, over many simulated seasons.
+type TeamOutlook struct {
+	Team TeamID
+
+	// The team's score in each simulated season, sorted from worst to best.
+	Points     []float32
+	MeanPoints float64
+
+	// The chance of finishing in each place, where [0] is first place.
+	// Teams tied for a place all get credit for it.
+	FinishProbability []float64
+
+	// For each scoring category, the chance of ranking in each place in it.
+	CategoryRankProbability map[StatID][]float64
+}
+
+// The score the team beat in the given fraction (0 to 1) of seasons.
+func (o *TeamOutlook) Percentile(p float64) float32 {
+	if len(o.Points) == 0 {
+		return 0
+	}
+	i := int(p * float64(len(o.Points)-1))
+	return o.Points[i]
+}
+
+type StandingsSimulation struct {
+	Trials int
+	Teams  map[TeamID]*TeamOutlook
+}
+
+// Teams ordered by their chance of winning the league, best first.
+func (s *StandingsSimulation) Standings() []TeamID {
+	teams := make([]TeamID, 0, len(s.Teams))
+	for team := range s.Teams {
+		teams = append(teams, team)
+	}
+	sort.Slice(teams, func(i, j int) bool {
+		a, b := s.Teams[teams[i]], s.Teams[teams[j]]
+		if a.FinishProbability[0] != b.FinishProbability[0] {
+			return a.FinishProbability[0] > b.FinishProbability[0]
+		}
+		if a.MeanPoints != b.MeanPoints {
+			return a.MeanPoints > b.MeanPoints
+		}
+		return teams[i] < teams[j]
+	})
+	return teams
+}
+
+// Simulates the rest of the season many times over, to see how likely each
+// team is to finish where.  Each simulated season samples every starter's
+// rest-of-season stats around their projection.
+func (fo *FO) SimulateStandings(ctx context.Context, now time.Time, trials int) (*StandingsSimulation, error) {
+	actual, rosters, complete, err := fo.seasonSoFar(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	rest := make(map[TeamID][]StatLine)
+	for team, roster := range rosters {
+		rest[team] = fo.projectStarterLines(roster, 1-complete)
+	}
+
+	return simulateStandings(
+		actual, rest, fo.settings.Categories, fo.settings.Scorer(), trials, now.UnixNano()), nil
+}
+
+// The results of one worker's share of the trials.
+type standingsTally struct {
+	points     map[TeamID][]float32
+	finishes   map[TeamID][]int
+	categories map[TeamID]map[StatID][]int
+}
+
+func newStandingsTally(teams []TeamID, categories ScoringCategories) *standingsTally {
+	tally := &standingsTally{
+		points:     make(map[TeamID][]float32),
+		finishes:   make(map[TeamID][]int),
+		categories: make(map[TeamID]map[StatID][]int),
+	}
+	for _, team := range teams {
+		tally.finishes[team] = make([]int, len(teams))
+		tally.categories[team] = make(map[StatID][]int)
+		for stat := range categories {
+			tally.categories[team][stat] = make([]int, len(teams))
+		}
+	}
+	return tally
+}
+
+func simulateStandings(actual map[TeamID]StatLine, rest map[TeamID][]StatLine, categories ScoringCategories, scorer Scorer, trials int, seed int64) *StandingsSimulation {
+	// Ranks in each category mean nothing in a points league.
+	if _, ok := scorer.(PointWeights); ok {
+		categories = ScoringCategories{}
+	}
+
+	teamSet := make(map[TeamID]bool)
+	for team := range actual {
+		teamSet[team] = true
+	}
+	for team := range rest {
+		teamSet[team] = true
+	}
+	teams := make([]TeamID, 0, len(teamSet))
+	for team := range teamSet {
+		teams = append(teams, team)
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i] < teams[j] })
+
+	trials = max(trials, 1)
+	workers := min(runtime.NumCPU(), trials)
+	tallies := make([]*standingsTally, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed + int64(w)))
+			tally := newStandingsTally(teams, categories)
+			// Spread the trials as evenly as possible over the workers.
+			for i := w; i < trials; i += workers {
+				simulateSeason(teams, actual, rest, categories, scorer, rng, tally)
+			}
+			tallies[w] = tally
+		}(w)
+	}
+	wg.Wait()
+
+	simulation := &StandingsSimulation{Trials: trials, Teams: make(map[TeamID]*TeamOutlook)}
+	for _, team := range teams {
+		outlook := &TeamOutlook{
+			Team:                    team,
+			FinishProbability:       make([]float64, len(teams)),
+			CategoryRankProbability: make(map[StatID][]float64),
+		}
+		for stat := range categories {
+			outlook.CategoryRankProbability[stat] = make([]float64, len(teams))
+		}
+
+		for _, tally := range tallies {
+			outlook.Points = append(outlook.Points, tally.points[team]...)
+			for place, n := range tally.finishes[team] {
+				outlook.FinishProbability[place] += float64(n) / float64(trials)
+			}
+			for stat, ranks := range tally.categories[team] {
+				for place, n := range ranks {
+					outlook.CategoryRankProbability[stat][place] += float64(n) / float64(trials)
+				}
+			}
+		}
+
+		sort.Slice(outlook.Points, func(i, j int) bool { return outlook.Points[i] < outlook.Points[j] })
+		total := 0.0
+		for _, p := range outlook.Points {
+			total += float64(p)
+		}
+		if len(outlook.Points) > 0 {
+			outlook.MeanPoints = total / float64(len(outlook.Points))
+		}
+
+		simulation.Teams[team] = outlook
+	}
+	return simulation
+}
+
+func simulateSeason(teams []TeamID, actual map[TeamID]StatLine, rest map[TeamID][]StatLine, categories ScoringCategories, scorer Scorer, rng *rand.Rand, tally *standingsTally) {
+	final := make(map[TeamID]StatLine)
+	for _, team := range teams {
+		lines := []StatLine{actual[team]}
+		for _, line := range rest[team] {
+			lines = append(lines, sampleStatLine(line, rng))
+		}
+		final[team] = merge(lines)
+	}
+
+	scores := scoreLeague(final, scorer)
+	for _, team := range teams {
+		tally.points[team] = append(tally.points[team], scores[team])
+		tally.finishes[team][placeOf(team, scores)]++
+	}
+
+	rawStats := make(map[string]StatLine)
+	for team, line := range final {
+		rawStats[strconv.Itoa(int(team))] = line
+	}
+	for stat, order := range categories {
+		rawScores := scoreStat(rawStats, stat, order)
+		scores := make(map[TeamID]float32)
+		for _, team := range teams {
+			scores[team] = rawScores[strconv.Itoa(int(team))]
+		}
+		for _, team := range teams {
+			tally.categories[team][stat][placeOf(team, scores)]++
+		}
+	}
+}
+
+// Where a team finished (0 for first place), given everyone's scores.  Tied
+// teams share the better place.
+func placeOf(team TeamID, scores map[TeamID]float32) int {
+	place := 0
+	for _, score := range scores {
+		if score > scores[team] {
+			place++
+		}
+	}
+	return place
+}
@@ -0,0 +1,143 @@
+package folib
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestSamplePoissonAndBinomial(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for _, mean := range []float64{4, 100} {
+		total, totalSq := 0.0, 0.0
+		n := 20000
+		for i := 0; i < n; i++ {
+			x := samplePoisson(mean, rng)
+			total += x
+			totalSq += x * x
+		}
+		m := total / float64(n)
+		v := totalSq/float64(n) - m*m
+		if math.Abs(m-mean) > 0.05*mean || math.Abs(v-mean) > 0.1*mean {
+			t.Errorf("Poisson(%f): got mean %f, variance %f", mean, m, v)
+		}
+	}
+
+	for _, trials := range []int{20, 600} {
+		p := 0.3
+		total, totalSq := 0.0, 0.0
+		n := 20000
+		for i := 0; i < n; i++ {
+			x := sampleBinomial(trials, p, rng)
+			if x < 0 || x > float64(trials) {
+				t.Fatalf("Binomial(%d, %f) out of range: %f", trials, p, x)
+			}
+			total += x
+			totalSq += x * x
+		}
+		m := total / float64(n)
+		v := totalSq/float64(n) - m*m
+		mean, variance := float64(trials)*p, float64(trials)*p*(1-p)
+		if math.Abs(m-mean) > 0.05*mean || math.Abs(v-variance) > 0.1*variance {
+			t.Errorf("Binomial(%d, %f): got mean %f, variance %f", trials, p, m, v)
+		}
+	}
+}
+
+func TestSampleStatLine(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	line := StatLine{B_AT_BATS: 500, B_HITS: 150, B_HOME_RUNS: 25, B_BATTING_AVG: 0.300}
+
+	for i := 0; i < 100; i++ {
+		sampled := sampleStatLine(line, rng)
+		if sampled[B_AT_BATS] != 500 {
+			t.Errorf("Playing time shouldn't vary, got %f AB", sampled[B_AT_BATS])
+		}
+		if !closeEnough(sampled[B_BATTING_AVG], sampled[B_HITS]/500) {
+			t.Errorf("Expected AVG to follow hits: %f H, %f AVG", sampled[B_HITS], sampled[B_BATTING_AVG])
+		}
+	}
+
+	// Without components, the rate is left alone.
+	sampled := sampleStatLine(StatLine{P_WHIP: 1.2}, rng)
+	if sampled[P_WHIP] != 1.2 {
+		t.Errorf("Expected WHIP to stay at 1.2, got: %f", sampled[P_WHIP])
+	}
+}
+
+func TestSimulateStandings(t *testing.T) {
+	categories := ScoringCategories{
+		B_HOME_RUNS:    HIGHER_IS_BETTER,
+		B_STOLEN_BASES: HIGHER_IS_BETTER,
+	}
+
+	// Team 1 has a big lead in home runs, and teams 2 and 3 are neck and
+	// neck in steals.
+	actual := map[TeamID]StatLine{
+		1: {B_HOME_RUNS: 150, B_STOLEN_BASES: 50},
+		2: {B_HOME_RUNS: 100, B_STOLEN_BASES: 80},
+		3: {B_HOME_RUNS: 100, B_STOLEN_BASES: 80},
+	}
+	rest := map[TeamID][]StatLine{
+		1: {{B_HOME_RUNS: 20, B_STOLEN_BASES: 5}},
+		2: {{B_HOME_RUNS: 20, B_STOLEN_BASES: 20}},
+		3: {{B_HOME_RUNS: 20, B_STOLEN_BASES: 20}},
+	}
+
+	simulation := simulateStandings(actual, rest, categories, categories, 4000, 1)
+	if simulation.Trials != 4000 {
+		t.Errorf("Expected 4000 trials, got: %d", simulation.Trials)
+	}
+
+	one := simulation.Teams[1]
+	if len(one.Points) != 4000 {
+		t.Errorf("Expected a score for every trial, got: %d", len(one.Points))
+	}
+	if one.CategoryRankProbability[B_HOME_RUNS][0] != 1 {
+		t.Errorf("Team 1 should always win HR, got: %v", one.CategoryRankProbability[B_HOME_RUNS])
+	}
+	if one.CategoryRankProbability[B_STOLEN_BASES][2] != 1 {
+		t.Errorf("Team 1 should always finish last in SB, got: %v", one.CategoryRankProbability[B_STOLEN_BASES])
+	}
+
+	two := simulation.Teams[2]
+	if p := two.CategoryRankProbability[B_STOLEN_BASES][0]; p < 0.4 || p > 0.7 {
+		t.Errorf("Team 2 should win SB about half the time (counting ties), got: %f", p)
+	}
+
+	for team, outlook := range simulation.Teams {
+		total := 0.0
+		for _, p := range outlook.FinishProbability {
+			total += p
+		}
+		if !closeEnough(Stat(total), 1) {
+			t.Errorf("Team %d's finishes should add up to 1, got: %f", team, total)
+		}
+		if outlook.Percentile(0.1) > outlook.Percentile(0.9) {
+			t.Errorf("Team %d's percentiles are out of order", team)
+		}
+	}
+}
+
+func TestSimulatePointsStandings(t *testing.T) {
+	categories := ScoringCategories{B_HOME_RUNS: HIGHER_IS_BETTER}
+	weights := PointWeights{B_HOME_RUNS: 4}
+	actual := map[TeamID]StatLine{
+		1: {B_HOME_RUNS: 150},
+		2: {B_HOME_RUNS: 100},
+	}
+	rest := map[TeamID][]StatLine{
+		1: {{B_HOME_RUNS: 20}},
+		2: {{B_HOME_RUNS: 20}},
+	}
+
+	simulation := simulateStandings(actual, rest, categories, weights, 100, 1)
+	one := simulation.Teams[1]
+	if one.FinishProbability[0] != 1 {
+		t.Errorf("Team 1 should always win, got: %v", one.FinishProbability)
+	}
+	if len(one.CategoryRankProbability) != 0 {
+		t.Errorf("Expected no category ranks in a points league, got: %v", one.CategoryRankProbability)
+	}
+}
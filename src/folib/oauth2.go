@@ -0,0 +1,371 @@
+package folib
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	YAHOO_AUTH_URL  = "https://api.login.yahoo.com/oauth2/request_auth"
+	YAHOO_TOKEN_URL = "https://api.login.yahoo.com/oauth2/get_token"
+
+	// Redirect URI which has Yahoo show the authorization code to the user,
+	// for them to paste back to us.
+	OOB_REDIRECT = "oob"
+
+	// Refresh tokens a little before they expire, so they don't expire
+	// mid-request.
+	TOKEN_EXPIRY_SLACK = time.Minute
+	// How long to wait for the user to authorize us in their browser.
+	AUTHORIZATION_TIMEOUT = 5 * time.Minute
+)
+
+// An OAuth2 bearer token, and what we need to renew it.
+type OAuth2Token struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	TokenType    string    `json:"token_type"`
+	Expiry       time.Time `json:"expiry"`
+	// What we've been granted access to, e.g. "fspt-w", if Yahoo says.
+	Scope string `json:"scope,omitempty"`
+}
+
+// Whether the token can still be used as of now.  Tokens without an expiry
+// never expire.
+func (t *OAuth2Token) Valid(now time.Time) bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || now.Add(TOKEN_EXPIRY_SLACK).Before(t.Expiry)
+}
+
+// Supplies access tokens for requests to Yahoo.
+type TokenProvider interface {
+	// A current access token, getting or renewing one first if need be.
+	Token(ctx context.Context) (*OAuth2Token, error)
+	// A new access token, for when Yahoo rejects the given one.  If the
+	// token has already been replaced, e.g. by a concurrent request which was
+	// also rejected, the replacement is returned as is.
+	Refresh(ctx context.Context, rejected *OAuth2Token) (*OAuth2Token, error)
+}
+
+// An app registered with Yahoo.
+type OAuth2Config struct {
+	ClientID     string
+	ClientSecret string
+	// Either OOB_REDIRECT, or a local URL (e.g. "http://localhost:8080/")
+	// which we listen on for Yahoo to send the user back to.
+	RedirectUrl string
+
+	AuthUrl  string
+	TokenUrl string
+}
+
+// Gets tokens from Yahoo with the authorization code flow, saves them to a
+// file, and refreshes them when they expire.
+type yahooTokenProvider struct {
+	config    OAuth2Config
+	tokenFile string
+	client    *http.Client
+	now       func() time.Time
+
+	// Shows the user where to authorize us.  With the OOB_REDIRECT, it also
+	// returns the code they get back.
+	prompt func(authUrl string) (code string, err error)
+
+	mu    sync.Mutex
+	token *OAuth2Token
+}
+
+func NewYahooTokenProvider(clientID, clientSecret, redirectUrl, tokenFile string) TokenProvider {
+	if redirectUrl == "" {
+		redirectUrl = OOB_REDIRECT
+	}
+	prompt := promptForCode
+	if redirectUrl != OOB_REDIRECT {
+		prompt = announceAuthUrl
+	}
+	return &yahooTokenProvider{
+		config: OAuth2Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectUrl:  redirectUrl,
+			AuthUrl:      YAHOO_AUTH_URL,
+			TokenUrl:     YAHOO_TOKEN_URL,
+		},
+		tokenFile: tokenFile,
+		client:    http.DefaultClient,
+		now:       time.Now,
+		prompt:    prompt,
+	}
+}
+
+func (p *yahooTokenProvider) Token(ctx context.Context) (*OAuth2Token, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token == nil {
+		p.token = p.loadToken()
+	}
+	if p.token.Valid(p.now()) {
+		return p.token, nil
+	}
+	if p.token != nil && p.token.RefreshToken != "" {
+		token, err := p.refresh(ctx)
+		if err == nil {
+			return token, nil
+		}
+		log.Printf("Couldn't refresh the Yahoo token, so reauthorizing: %s", err)
+	}
+	return p.authorize(ctx)
+}
+
+func (p *yahooTokenProvider) Refresh(ctx context.Context, rejected *OAuth2Token) (*OAuth2Token, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token != nil && rejected != nil && p.token.AccessToken != rejected.AccessToken {
+		return p.token, nil
+	}
+
+	if p.token == nil || p.token.RefreshToken == "" {
+		return p.authorize(ctx)
+	}
+	return p.refresh(ctx)
+}
+
+func (p *yahooTokenProvider) refresh(ctx context.Context) (*OAuth2Token, error) {
+	token, err := p.requestToken(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {p.token.RefreshToken},
+		"redirect_uri":  {p.config.RedirectUrl},
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Yahoo doesn't always send a new refresh token, or the scope.
+	if token.RefreshToken == "" {
+		token.RefreshToken = p.token.RefreshToken
+	}
+	if token.Scope == "" {
+		token.Scope = p.token.Scope
+	}
+	return token, p.saveToken(token)
+}
+
+// Sends the user off to Yahoo to grant us access, and trades the code they
+// come back with for a token.
+func (p *yahooTokenProvider) authorize(ctx context.Context) (*OAuth2Token, error) {
+	state, err := randomState()
+	if err != nil {
+		return nil, err
+	}
+	authUrl := p.config.AuthUrl + "?" + url.Values{
+		"client_id":     {p.config.ClientID},
+		"redirect_uri":  {p.config.RedirectUrl},
+		"response_type": {"code"},
+		"state":         {state},
+	}.Encode()
+
+	var code string
+	if p.config.RedirectUrl == OOB_REDIRECT {
+		code, err = p.prompt(authUrl)
+	} else {
+		code, err = receiveCode(ctx, p.config.RedirectUrl, state, func() error {
+			_, err := p.prompt(authUrl)
+			return err
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if code == "" {
+		return nil, fmt.Errorf("No authorization code")
+	}
+
+	token, err := p.requestToken(ctx, url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {p.config.RedirectUrl},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return token, p.saveToken(token)
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	Scope        string `json:"scope"`
+
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (p *yahooTokenProvider) requestToken(ctx context.Context, form url.Values) (*OAuth2Token, error) {
+	request, err := http.NewRequestWithContext(ctx, "POST", p.config.TokenUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.SetBasicAuth(p.config.ClientID, p.config.ClientSecret)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	bits, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var data tokenResponse
+	if err := json.Unmarshal(bits, &data); err != nil {
+		return nil, fmt.Errorf("Bad token response (%s): %s", response.Status, string(bits))
+	}
+	if data.Error != "" {
+		return nil, fmt.Errorf("Token request failed: %s: %s", data.Error, data.ErrorDescription)
+	}
+	if response.StatusCode != http.StatusOK || data.AccessToken == "" {
+		return nil, fmt.Errorf("Token request failed (%s): %s", response.Status, string(bits))
+	}
+
+	token := &OAuth2Token{
+		AccessToken:  data.AccessToken,
+		RefreshToken: data.RefreshToken,
+		TokenType:    data.TokenType,
+		Scope:        data.Scope,
+	}
+	if data.ExpiresIn > 0 {
+		token.Expiry = p.now().Add(time.Duration(data.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// The saved token, or nil if there isn't a usable one.  Tokens saved in an
+// older format are rewritten in the current one.
+func (p *yahooTokenProvider) loadToken() *OAuth2Token {
+	if p.tokenFile == "" {
+		return nil
+	}
+	token, version, err := ReadTokenFile(p.tokenFile)
+	if err != nil {
+		log.Printf("Ignoring token file %s: %s", p.tokenFile, err)
+		return nil
+	}
+
+	if token != nil && version < TOKEN_FILE_VERSION {
+		if err := WriteTokenFile(p.tokenFile, token); err != nil {
+			log.Printf("Couldn't update token file %s: %s", p.tokenFile, err)
+		}
+	}
+	return token
+}
+
+func (p *yahooTokenProvider) saveToken(token *OAuth2Token) error {
+	p.token = token
+	if p.tokenFile == "" {
+		return nil
+	}
+	return WriteTokenFile(p.tokenFile, token)
+}
+
+func randomState() (string, error) {
+	bits := make([]byte, 16)
+	if _, err := rand.Read(bits); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bits), nil
+}
+
+func promptForCode(authUrl string) (string, error) {
+	fmt.Println("(1) Go to: " + authUrl)
+	fmt.Println("(2) Grant access, you should get back a verification code.")
+	fmt.Println("(3) Enter that verification code here: ")
+
+	code := ""
+	_, err := fmt.Scanln(&code)
+	return code, err
+}
+
+// Listens on a local redirect URL for Yahoo to send the user back with an
+// authorization code.  visit is called once we're listening, to send the
+// user to Yahoo.
+func receiveCode(ctx context.Context, redirectUrl, state string, visit func() error) (string, error) {
+	redirect, err := url.Parse(redirectUrl)
+	if err != nil {
+		return "", err
+	}
+	listener, err := net.Listen("tcp", redirect.Host)
+	if err != nil {
+		return "", err
+	}
+	defer listener.Close()
+
+	codes := make(chan string, 1)
+	errors := make(chan error, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case r.URL.Path != redirect.Path && redirect.Path != "":
+			http.NotFound(w, r)
+			return
+		case query.Get("state") != state:
+			http.Error(w, "Wrong state", http.StatusBadRequest)
+			return
+		case query.Get("error") != "":
+			fmt.Fprintln(w, "Authorization failed, you can close this window.")
+			select {
+			case errors <- fmt.Errorf("Authorization failed: %s", query.Get("error")):
+			default:
+			}
+			return
+		}
+		fmt.Fprintln(w, "Authorized, you can close this window.")
+		select {
+		case codes <- query.Get("code"):
+		default:
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	if err := visit(); err != nil {
+		return "", err
+	}
+
+	select {
+	case code := <-codes:
+		return code, nil
+	case err := <-errors:
+		return "", err
+	case <-time.After(AUTHORIZATION_TIMEOUT):
+		return "", fmt.Errorf("Timed out waiting for authorization")
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// Prints where to authorize us, for when we're listening for the redirect
+// rather than waiting for a code on stdin.
+func announceAuthUrl(authUrl string) (string, error) {
+	fmt.Println("Go to: " + authUrl)
+	fmt.Println("Waiting for Yahoo to send you back here...")
+	return "", nil
+}
@@ -0,0 +1,224 @@
+package folib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestYahooClientRefreshesRejectedToken(t *testing.T) {
+	yahoo, client := newFakeYahoo(t)
+	yahoo.respond("/game/mlb", `<?xml version="1.0"?><fantasy_content><game><game_key>328</game_key></game></fantasy_content>`)
+	yahoo.expireToken("new token")
+
+	games, err := client.GetGames(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 1 {
+		t.Errorf("Expected the retried request to succeed, got: %v", games)
+	}
+	if yahoo.tokens.refreshes != 1 {
+		t.Errorf("Expected one refresh, got %d", yahoo.tokens.refreshes)
+	}
+}
+
+func TestYahooClientRefreshesOnceForConcurrentRequests(t *testing.T) {
+	yahoo, client := newFakeYahoo(t)
+	yahoo.respond("/game/mlb", `<?xml version="1.0"?><fantasy_content><game><game_key>328</game_key></game></fantasy_content>`)
+	yahoo.expireToken("new token")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GetGames(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if yahoo.tokens.refreshes != 1 {
+		t.Errorf("Expected one refresh, got %d", yahoo.tokens.refreshes)
+	}
+}
+
+// A fake Yahoo token endpoint, which records the grants it's asked for.
+type fakeTokenServer struct {
+	server *httptest.Server
+	grants []url.Values
+}
+
+func newFakeTokenServer(t *testing.T) *fakeTokenServer {
+	f := &fakeTokenServer{}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "id" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error": "invalid_client"}`)
+			return
+		}
+		r.ParseForm()
+		f.grants = append(f.grants, r.PostForm)
+
+		switch r.PostForm.Get("grant_type") {
+		case "authorization_code":
+			fmt.Fprintf(w, `{"access_token": "access %s", "refresh_token": "refresh", "expires_in": 3600, "token_type": "bearer"}`,
+				r.PostForm.Get("code"))
+		case "refresh_token":
+			// Yahoo doesn't send the refresh token back.
+			fmt.Fprintf(w, `{"access_token": "refreshed %d", "expires_in": 3600, "token_type": "bearer"}`, len(f.grants))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error": "unsupported_grant_type"}`)
+		}
+	}))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func testTokenProvider(tokenServer *fakeTokenServer, redirectUrl, tokenFile string, prompt func(string) (string, error)) *yahooTokenProvider {
+	p := NewYahooTokenProvider("id", "secret", redirectUrl, tokenFile).(*yahooTokenProvider)
+	p.config.TokenUrl = tokenServer.server.URL
+	p.prompt = prompt
+	return p
+}
+
+func TestTokenProviderAuthorizes(t *testing.T) {
+	tokenServer := newFakeTokenServer(t)
+	tokenFile := filepath.Join(t.TempDir(), "token")
+
+	prompts := 0
+	p := testTokenProvider(tokenServer, OOB_REDIRECT, tokenFile, func(authUrl string) (string, error) {
+		prompts++
+		u, _ := url.Parse(authUrl)
+		if u.Query().Get("client_id") != "id" || u.Query().Get("redirect_uri") != "oob" {
+			t.Errorf("Bad authorization URL: %s", authUrl)
+		}
+		return "code", nil
+	})
+
+	token, err := p.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "access code" || token.RefreshToken != "refresh" {
+		t.Errorf("Wrong token: %v", token)
+	}
+
+	// The token is saved, readable only by us, and reused.
+	info, err := os.Stat(tokenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the token file to be private, got %v", info.Mode())
+	}
+	p = testTokenProvider(tokenServer, OOB_REDIRECT, tokenFile, func(string) (string, error) {
+		prompts++
+		return "", fmt.Errorf("Shouldn't ask again")
+	})
+	token, err = p.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "access code" || prompts != 1 {
+		t.Errorf("Expected the saved token, got %v after %d prompts", token, prompts)
+	}
+}
+
+func TestTokenProviderRefreshes(t *testing.T) {
+	tokenServer := newFakeTokenServer(t)
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	expired := OAuth2Token{AccessToken: "old", RefreshToken: "refresh", Expiry: time.Now().Add(-time.Hour)}
+	bits, _ := json.Marshal(expired)
+	if err := ioutil.WriteFile(tokenFile, bits, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := testTokenProvider(tokenServer, OOB_REDIRECT, tokenFile, func(string) (string, error) {
+		return "", fmt.Errorf("Shouldn't need to authorize")
+	})
+	token, err := p.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "refreshed 1" || token.RefreshToken != "refresh" {
+		t.Errorf("Expected a refreshed token keeping the old refresh token, got: %v", token)
+	}
+	if !token.Valid(time.Now()) {
+		t.Errorf("Expected the refreshed token to be valid, expires %v", token.Expiry)
+	}
+	if len(tokenServer.grants) != 1 || tokenServer.grants[0].Get("refresh_token") != "refresh" {
+		t.Errorf("Expected one refresh grant, got: %v", tokenServer.grants)
+	}
+
+	// Forcing a refresh, e.g. after a 401, asks again even if the token
+	// looks fine.
+	refreshed, err := p.Refresh(context.Background(), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokenServer.grants) != 2 {
+		t.Errorf("Expected a second refresh grant, got: %v", tokenServer.grants)
+	}
+
+	// But not for a token that's already been replaced, e.g. when several
+	// requests were rejected at once.
+	current, err := p.Refresh(context.Background(), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current != refreshed || len(tokenServer.grants) != 2 {
+		t.Errorf("Expected the current token without another grant, got %v after %d grants", current, len(tokenServer.grants))
+	}
+}
+
+func TestTokenProviderListensForRedirect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	redirectUrl := fmt.Sprintf("http://%s/callback", listener.Addr())
+	listener.Close()
+
+	tokenServer := newFakeTokenServer(t)
+	p := testTokenProvider(tokenServer, redirectUrl, "", func(authUrl string) (string, error) {
+		// Play the part of the browser, coming back from Yahoo.
+		u, _ := url.Parse(authUrl)
+		state := u.Query().Get("state")
+		go func() {
+			forged, err := http.Get(redirectUrl + "?code=forged&state=wrong")
+			if err == nil {
+				forged.Body.Close()
+			}
+			response, err := http.Get(redirectUrl + "?code=browser&state=" + state)
+			if err == nil {
+				response.Body.Close()
+			}
+		}()
+		return "", nil
+	})
+
+	token, err := p.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "access browser" {
+		t.Errorf("Expected the code from the redirect, got: %v", token)
+	}
+	if tokenServer.grants[0].Get("redirect_uri") != redirectUrl {
+		t.Errorf("Expected the redirect URI to be sent with the code, got: %v", tokenServer.grants[0])
+	}
+}
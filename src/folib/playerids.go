@@ -0,0 +1,608 @@
+package folib
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Everything we know about who a player is, across all of our data sources.
+type PlayerRecord struct {
+	// The key the stats source uses for this player.  This is usually just
+	// their name, but players who share a name are disambiguated (e.g.
+	// "Chris Young [Mets]").
+	ID   PlayerID
+	Name string
+	Team string
+	Side string // BATTING or PITCHING
+
+	YahooKey    string
+	FanGraphsID string
+	MLBAMID     string
+}
+
+// A StatsClient which can list all the players it knows about.
+type PlayerDirectory interface {
+	Players() []PlayerRecord
+}
+
+// One row from a stats source, before we've assigned it a PlayerID.
+type playerRow struct {
+	record PlayerRecord
+	stats  StatLine
+}
+
+// Gives every row a unique PlayerID, and builds the per-side indexes that
+// StatsClients look players up in.
+//
+// Most players are identified just by name.  Names that appear more than once
+// (e.g. the two Chris Youngs, or a batter and pitcher with the same name) get
+// the player's team appended, or failing that which side they play.
+func indexPlayerRows(batting, pitching []playerRow) (*map[PlayerID]StatLine, *map[PlayerID]StatLine, []PlayerRecord) {
+	nameCounts := make(map[string]int)
+	for _, rows := range [][]playerRow{batting, pitching} {
+		for _, row := range rows {
+			nameCounts[row.record.Name]++
+		}
+	}
+
+	ids := make(map[PlayerID]bool)
+	players := []PlayerRecord{}
+	index := func(rows []playerRow) *map[PlayerID]StatLine {
+		stats := make(map[PlayerID]StatLine)
+		for _, row := range rows {
+			record := row.record
+			record.ID = PlayerID(record.Name)
+			if nameCounts[record.Name] > 1 && record.Team != "" {
+				record.ID = PlayerID(fmt.Sprintf("%s [%s]", record.Name, record.Team))
+			}
+			if ids[record.ID] {
+				record.ID = PlayerID(fmt.Sprintf("%s [%s]", record.ID, record.Side))
+			}
+			for n := 2; ids[record.ID]; n++ {
+				record.ID = PlayerID(fmt.Sprintf("%s [%s %d]", record.Name, record.Side, n))
+			}
+			ids[record.ID] = true
+			stats[record.ID] = row.stats
+			players = append(players, record)
+		}
+		return &stats
+	}
+
+	battingStats := index(batting)
+	pitchingStats := index(pitching)
+	return battingStats, pitchingStats, players
+}
+
+// The position of the first of the given columns present in a CSV header, or
+// -1 if none of them are.
+func findColumn(header []string, names ...string) int {
+	for _, name := range names {
+		for i, col := range header {
+			if col == name {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+//
+// Name and team normalization
+//
+
+var accents = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a", "å", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ñ", "n", "ç", "c", "ý", "y", "ÿ", "y",
+)
+
+var nameSuffixes = map[string]bool{
+	"jr": true, "sr": true, "ii": true, "iii": true, "iv": true,
+}
+
+// Common nicknames, mapped to the name they're short for.  Sources disagree
+// about which one to use (e.g. "Mike" vs "Michael").
+var nicknames = map[string]string{
+	"alex":  "alexander",
+	"ben":   "benjamin",
+	"bob":   "robert",
+	"chris": "christopher",
+	"dan":   "daniel",
+	"danny": "daniel",
+	"dave":  "david",
+	"ed":    "edward",
+	"greg":  "gregory",
+	"jake":  "jacob",
+	"jim":   "james",
+	"jimmy": "james",
+	"joe":   "joseph",
+	"jon":   "jonathan",
+	"josh":  "joshua",
+	"matt":  "matthew",
+	"mike":  "michael",
+	"nick":  "nicholas",
+	"rob":   "robert",
+	"steve": "steven",
+	"tom":   "thomas",
+	"tony":  "anthony",
+	"will":  "william",
+	"zach":  "zachary",
+}
+
+// Reduces a name to a canonical form for comparison, e.g.
+// "José Abreu Jr." -> "jose abreu" and "A. J. Pollock" -> "aj pollock".
+func normalizeName(name string) string {
+	name = accents.Replace(strings.ToLower(name))
+	name = strings.NewReplacer(".", " ", "'", "", "-", " ", ",", " ").Replace(name)
+
+	tokens := []string{}
+	initials := ""
+	for _, token := range strings.Fields(name) {
+		// Run initials together, so "a j" matches "aj".
+		if len(token) == 1 {
+			initials += token
+			continue
+		}
+		if initials != "" {
+			tokens = append(tokens, initials)
+			initials = ""
+		}
+		tokens = append(tokens, token)
+	}
+	if initials != "" {
+		tokens = append(tokens, initials)
+	}
+
+	for len(tokens) > 1 && nameSuffixes[tokens[len(tokens)-1]] {
+		tokens = tokens[:len(tokens)-1]
+	}
+
+	if len(tokens) > 1 {
+		if full, ok := nicknames[tokens[0]]; ok {
+			tokens[0] = full
+		}
+	}
+
+	return strings.Join(tokens, " ")
+}
+
+// Every way our sources refer to each team, keyed by the standard
+// abbreviation.
+var teamAliases = map[string][]string{
+	"ARI": {"ari", "az", "arizona", "diamondbacks", "dbacks"},
+	"ATL": {"atl", "atlanta", "braves"},
+	"BAL": {"bal", "baltimore", "orioles"},
+	"BOS": {"bos", "boston", "red sox"},
+	"CHC": {"chc", "chn", "cubs"},
+	"CWS": {"cws", "chw", "cha", "white sox"},
+	"CIN": {"cin", "cincinnati", "reds"},
+	"CLE": {"cle", "cleveland", "indians", "guardians"},
+	"COL": {"col", "colorado", "rockies"},
+	"DET": {"det", "detroit", "tigers"},
+	"HOU": {"hou", "houston", "astros"},
+	"KC":  {"kc", "kcr", "kca", "kansas city", "royals"},
+	"LAA": {"laa", "ana", "anaheim", "angels"},
+	"LAD": {"lad", "lan", "dodgers"},
+	"MIA": {"mia", "fla", "miami", "florida", "marlins"},
+	"MIL": {"mil", "milwaukee", "brewers"},
+	"MIN": {"min", "minnesota", "twins"},
+	"NYM": {"nym", "nyn", "mets"},
+	"NYY": {"nyy", "nya", "yankees"},
+	"OAK": {"oak", "ath", "oakland", "athletics", "as"},
+	"PHI": {"phi", "philadelphia", "phillies"},
+	"PIT": {"pit", "pittsburgh", "pirates"},
+	"SD":  {"sd", "sdp", "sdn", "san diego", "padres"},
+	"SF":  {"sf", "sfg", "sfn", "san francisco", "giants"},
+	"SEA": {"sea", "seattle", "mariners"},
+	"STL": {"stl", "sln", "st louis", "cardinals"},
+	"TB":  {"tb", "tbr", "tba", "tampa bay", "rays", "devil rays"},
+	"TEX": {"tex", "texas", "rangers"},
+	"TOR": {"tor", "toronto", "blue jays"},
+	"WSH": {"wsh", "was", "wsn", "washington", "nationals"},
+}
+
+var teamsByAlias = indexTeamAliases(teamAliases)
+
+func indexTeamAliases(aliases map[string][]string) map[string]string {
+	index := make(map[string]string)
+	for abbr, names := range aliases {
+		for _, name := range names {
+			index[name] = abbr
+		}
+	}
+	return index
+}
+
+// Maps any of the ways sources refer to a team ("Det", "Tigers", "Detroit
+// Tigers") to its standard abbreviation, or "" if we don't recognize it.
+func normalizeTeam(team string) string {
+	team = strings.TrimSpace(strings.NewReplacer(".", "", "'", "", "-", "").Replace(strings.ToLower(team)))
+	if abbr, ok := teamsByAlias[team]; ok {
+		return abbr
+	}
+	// Full names, e.g. "Boston Red Sox", end with the nickname.
+	for alias, abbr := range teamsByAlias {
+		if strings.Contains(alias, " ") || len(alias) > 3 {
+			if strings.HasSuffix(team, " "+alias) {
+				return abbr
+			}
+		}
+	}
+	return ""
+}
+
+//
+// Matching
+//
+
+// An override which pins a Yahoo player to a particular record in our stats
+// sources, for players the matcher can't figure out on its own.
+type PlayerOverride struct {
+	YahooKey    string
+	ID          PlayerID
+	FanGraphsID string
+	MLBAMID     string
+}
+
+// A rostered player we couldn't match to our stats sources.
+type UnmatchedPlayer struct {
+	Player YahooPlayer
+	// Players who might be them.  Empty if we didn't find anyone close.
+	Candidates []PlayerRecord
+}
+
+// Figures out which player in our stats sources each Yahoo player is.
+//
+// In order, we try: the override file; an exact match on normalized name; and
+// a fuzzy match on normalized name.  When several players match, we narrow
+// them down by side (batter or pitcher) and then team.  Each Yahoo player is
+// only matched once; after that we remember the answer.
+//
+// A PlayerResolver is safe to use from multiple goroutines.
+type PlayerResolver struct {
+	// Never change after construction, so matching doesn't need the lock.
+	records []PlayerRecord
+	byName  map[string][]int
+	byID    map[PlayerID]int
+
+	mu        sync.Mutex
+	overrides map[string]PlayerOverride
+	resolved  map[string]resolution // By Yahoo player key
+	unmatched map[string]UnmatchedPlayer
+}
+
+type resolution struct {
+	record PlayerRecord
+	ok     bool
+}
+
+func NewPlayerResolver(records []PlayerRecord, overrides []PlayerOverride) *PlayerResolver {
+	r := &PlayerResolver{
+		records:   records,
+		byName:    make(map[string][]int),
+		byID:      make(map[PlayerID]int),
+		overrides: make(map[string]PlayerOverride),
+		resolved:  make(map[string]resolution),
+		unmatched: make(map[string]UnmatchedPlayer),
+	}
+	for i, record := range records {
+		name := normalizeName(record.Name)
+		r.byName[name] = append(r.byName[name], i)
+		r.byID[record.ID] = i
+	}
+	for _, o := range overrides {
+		r.overrides[o.YahooKey] = o
+	}
+	return r
+}
+
+// Returns the record for a Yahoo player, and whether we found one.
+func (r *PlayerResolver) Resolve(player YahooPlayer) (PlayerRecord, bool) {
+	key := player.PlayerKey
+	r.mu.Lock()
+	if resolved, ok := r.resolved[key]; ok && key != "" {
+		r.mu.Unlock()
+		return resolved.record, resolved.ok
+	}
+	o, overridden := r.overrides[key]
+	r.mu.Unlock()
+
+	// The fuzzy match compares against every name we know, so it's done
+	// without holding up everyone else.
+	var resolved resolution
+	var candidates []int
+	if overridden {
+		resolved = resolution{record: r.overridden(o, player), ok: true}
+	} else {
+		resolved, candidates = r.match(player)
+	}
+
+	r.mu.Lock()
+	if current, ok := r.overrides[key]; ok != overridden || current != o {
+		// Someone added an override while we were matching.
+		r.mu.Unlock()
+		return r.Resolve(player)
+	}
+	defer r.mu.Unlock()
+
+	if key != "" {
+		r.resolved[key] = resolved
+	}
+	// Players without a key are only reported, by name.
+	unmatchedKey := key
+	if key == "" {
+		unmatchedKey = "name:" + player.FullName
+	}
+	if resolved.ok {
+		delete(r.unmatched, unmatchedKey)
+	} else {
+		unmatched := UnmatchedPlayer{Player: player}
+		for _, i := range candidates {
+			unmatched.Candidates = append(unmatched.Candidates, r.records[i])
+		}
+		r.unmatched[unmatchedKey] = unmatched
+	}
+	return resolved.record, resolved.ok
+}
+
+func (r *PlayerResolver) overridden(o PlayerOverride, player YahooPlayer) PlayerRecord {
+	record := PlayerRecord{ID: o.ID, Name: player.FullName, Side: player.PositionType}
+	if i, ok := r.byID[o.ID]; ok {
+		record = r.records[i]
+	}
+	record.YahooKey = player.PlayerKey
+	if o.FanGraphsID != "" {
+		record.FanGraphsID = o.FanGraphsID
+	}
+	if o.MLBAMID != "" {
+		record.MLBAMID = o.MLBAMID
+	}
+	return record
+}
+
+// Matches a player by name, returning the candidates if there wasn't exactly
+// one.
+func (r *PlayerResolver) match(player YahooPlayer) (resolution, []int) {
+	name := normalizeName(player.FullName)
+	candidates, ok := r.byName[name]
+	if !ok {
+		candidates = r.fuzzyCandidates(name)
+	}
+	candidates = r.disambiguate(candidates, player)
+
+	if len(candidates) != 1 {
+		return resolution{}, candidates
+	}
+	record := r.records[candidates[0]]
+	record.YahooKey = player.PlayerKey
+	return resolution{record: record, ok: true}, candidates
+}
+
+// The PlayerID our stats sources use for a Yahoo player.  Players we can't
+// match fall back to their name, which is what we would have tried anyway.
+func (r *PlayerResolver) PlayerID(player YahooPlayer) PlayerID {
+	if record, ok := r.Resolve(player); ok {
+		return record.ID
+	}
+	return PlayerID(player.FullName)
+}
+
+// Every player that has failed to resolve, sorted by name.
+func (r *PlayerResolver) Unmatched() []UnmatchedPlayer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	unmatched := make([]UnmatchedPlayer, 0, len(r.unmatched))
+	for _, u := range r.unmatched {
+		unmatched = append(unmatched, u)
+	}
+	sort.Slice(unmatched, func(i, j int) bool {
+		return unmatched[i].Player.FullName < unmatched[j].Player.FullName
+	})
+	return unmatched
+}
+
+// Pins a Yahoo player to a record, e.g. after a human has resolved an
+// ambiguous match.
+func (r *PlayerResolver) AddOverride(o PlayerOverride) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.overrides[o.YahooKey] = o
+	delete(r.resolved, o.YahooKey)
+	delete(r.unmatched, o.YahooKey)
+}
+
+// Allow roughly one typo per eight characters.
+func maxEditDistance(name string) int {
+	d := len(name) / 8
+	if d < 1 {
+		return 1
+	}
+	return d
+}
+
+func (r *PlayerResolver) fuzzyCandidates(name string) []int {
+	best := maxEditDistance(name) + 1
+	candidates := []int{}
+	for other, indices := range r.byName {
+		d := editDistance(name, other)
+		if d < best {
+			best = d
+			candidates = []int{}
+		}
+		if d == best {
+			candidates = append(candidates, indices...)
+		}
+	}
+	sort.Ints(candidates)
+	return candidates
+}
+
+// Narrows down a set of candidates using the player's side and team.  Each
+// filter is only applied if it leaves someone.
+func (r *PlayerResolver) disambiguate(candidates []int, player YahooPlayer) []int {
+	filter := func(candidates []int, keep func(PlayerRecord) bool) []int {
+		if len(candidates) <= 1 {
+			return candidates
+		}
+		kept := []int{}
+		for _, i := range candidates {
+			if keep(r.records[i]) {
+				kept = append(kept, i)
+			}
+		}
+		if len(kept) == 0 {
+			return candidates
+		}
+		return kept
+	}
+
+	candidates = filter(candidates, func(record PlayerRecord) bool {
+		return record.Side == player.PositionType
+	})
+
+	team := normalizeTeam(player.TeamAbbr)
+	if team == "" {
+		team = normalizeTeam(player.TeamName)
+	}
+	if team != "" {
+		candidates = filter(candidates, func(record PlayerRecord) bool {
+			return normalizeTeam(record.Team) == team
+		})
+	}
+
+	return candidates
+}
+
+// Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	cur := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		cur[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(br)]
+}
+
+//
+// Override file
+//
+
+// Reads overrides from a CSV file with the columns yahoo_key, player_id, and
+// optionally fangraphs_id and mlbam_id.  Lines starting with '#' are ignored.
+// A missing file has no overrides.
+func LoadPlayerOverrides(filename string) ([]PlayerOverride, error) {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return []PlayerOverride{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readPlayerOverrides(f)
+}
+
+func readPlayerOverrides(f io.Reader) ([]PlayerOverride, error) {
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	recs, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := []PlayerOverride{}
+	for i, rec := range recs {
+		if len(rec) < 2 {
+			return nil, fmt.Errorf("Line %d: expected at least yahoo_key,player_id", i+1)
+		}
+		o := PlayerOverride{YahooKey: rec[0], ID: PlayerID(rec[1])}
+		if len(rec) > 2 {
+			o.FanGraphsID = rec[2]
+		}
+		if len(rec) > 3 {
+			o.MLBAMID = rec[3]
+		}
+		overrides = append(overrides, o)
+	}
+	return overrides, nil
+}
+
+// Writes overrides in the format LoadPlayerOverrides reads.  Players we
+// couldn't match go after them, commented out once for each candidate, so
+// fixing one is a matter of uncommenting the right line.
+func SavePlayerOverrides(filename string, overrides []PlayerOverride, unmatched []UnmatchedPlayer) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return writePlayerOverrides(f, overrides, unmatched)
+}
+
+func writePlayerOverrides(f io.Writer, overrides []PlayerOverride, unmatched []UnmatchedPlayer) error {
+	fmt.Fprintln(f, "# yahoo_key,player_id,fangraphs_id,mlbam_id")
+	w := csv.NewWriter(f)
+	for _, o := range overrides {
+		err := w.Write([]string{o.YahooKey, string(o.ID), o.FanGraphsID, o.MLBAMID})
+		if err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+
+	for _, u := range unmatched {
+		fmt.Fprintf(f, "\n# Unmatched: %s (%s %s)\n", u.Player.FullName, u.Player.TeamAbbr, u.Player.PositionType)
+		for _, candidate := range u.Candidates {
+			var line strings.Builder
+			w := csv.NewWriter(&line)
+			w.Write([]string{u.Player.PlayerKey, string(candidate.ID), candidate.FanGraphsID, candidate.MLBAMID})
+			w.Flush()
+			if _, err := fmt.Fprintf(f, "#%s", line.String()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Every override the resolver knows about, sorted by Yahoo key.
+func (r *PlayerResolver) Overrides() []PlayerOverride {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	overrides := make([]PlayerOverride, 0, len(r.overrides))
+	for _, o := range r.overrides {
+		overrides = append(overrides, o)
+	}
+	sort.Slice(overrides, func(i, j int) bool {
+		return overrides[i].YahooKey < overrides[j].YahooKey
+	})
+	return overrides
+}
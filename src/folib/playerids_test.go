@@ -0,0 +1,194 @@
+package folib
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeName(t *testing.T) {
+	cases := map[string]string{
+		"José Abreu":            "jose abreu",
+		"Vladimir Guerrero Jr.": "vladimir guerrero",
+		"A. J. Pollock":         "aj pollock",
+		"AJ Pollock":            "aj pollock",
+		"Mike Trout":            "michael trout",
+		"Michael Trout":         "michael trout",
+		"Travis d'Arnaud":       "travis darnaud",
+		"Ke'Bryan Hayes":        "kebryan hayes",
+	}
+	for in, expected := range cases {
+		if actual := normalizeName(in); actual != expected {
+			t.Errorf("normalizeName(%q): expected %q, got %q", in, expected, actual)
+		}
+	}
+}
+
+func TestNormalizeTeam(t *testing.T) {
+	cases := map[string]string{
+		"Det":            "DET",
+		"Tigers":         "DET",
+		"Detroit Tigers": "DET",
+		"Boston Red Sox": "BOS",
+		"WSN":            "WSH",
+		"Nowhere":        "",
+	}
+	for in, expected := range cases {
+		if actual := normalizeTeam(in); actual != expected {
+			t.Errorf("normalizeTeam(%q): expected %q, got %q", in, expected, actual)
+		}
+	}
+}
+
+func testResolver() *PlayerResolver {
+	return NewPlayerResolver([]PlayerRecord{
+		{ID: "José Abreu", Name: "José Abreu", Team: "White Sox", Side: BATTING},
+		{ID: "Chris Young [Mariners]", Name: "Chris Young", Team: "Mariners", Side: PITCHING},
+		{ID: "Chris Young [Mets]", Name: "Chris Young", Team: "Mets", Side: BATTING},
+		{ID: "Chris Young [Yankees]", Name: "Chris Young", Team: "Yankees", Side: BATTING},
+		{ID: "Giancarlo Stanton", Name: "Giancarlo Stanton", Team: "Marlins", Side: BATTING},
+	}, []PlayerOverride{})
+}
+
+func TestResolveByName(t *testing.T) {
+	r := testResolver()
+
+	record, ok := r.Resolve(YahooPlayer{PlayerKey: "1", FullName: "Jose Abreu", PositionType: "B"})
+	if !ok || record.ID != "José Abreu" {
+		t.Errorf("Expected to match José Abreu, got: %v %v", record, ok)
+	}
+	if record.YahooKey != "1" {
+		t.Errorf("Expected the Yahoo key to be filled in, got: %q", record.YahooKey)
+	}
+
+	record, ok = r.Resolve(YahooPlayer{PlayerKey: "2", FullName: "Giancarlo Stantn", PositionType: "B"})
+	if !ok || record.ID != "Giancarlo Stanton" {
+		t.Errorf("Expected a fuzzy match on Giancarlo Stanton, got: %v %v", record, ok)
+	}
+}
+
+func TestResolveDisambiguates(t *testing.T) {
+	r := testResolver()
+
+	id := r.PlayerID(YahooPlayer{PlayerKey: "3", FullName: "Chris Young", PositionType: "P"})
+	if id != "Chris Young [Mariners]" {
+		t.Errorf("Expected the pitcher, got: %s", id)
+	}
+
+	id = r.PlayerID(YahooPlayer{PlayerKey: "4", FullName: "Chris Young", PositionType: "B", TeamAbbr: "NYY"})
+	if id != "Chris Young [Yankees]" {
+		t.Errorf("Expected the Yankee, got: %s", id)
+	}
+
+	id = r.PlayerID(YahooPlayer{PlayerKey: "5", FullName: "Chris Young", PositionType: "B", TeamName: "New York Mets"})
+	if id != "Chris Young [Mets]" {
+		t.Errorf("Expected the Met, got: %s", id)
+	}
+}
+
+func TestResolveReportsUnmatched(t *testing.T) {
+	r := testResolver()
+
+	// Two batters, and no team to tell them apart.
+	ambiguous := YahooPlayer{PlayerKey: "6", FullName: "Chris Young", PositionType: "B"}
+	if _, ok := r.Resolve(ambiguous); ok {
+		t.Errorf("Expected an ambiguous match to fail")
+	}
+	if id := r.PlayerID(ambiguous); id != "Chris Young" {
+		t.Errorf("Expected unmatched players to fall back to their name, got: %s", id)
+	}
+
+	missing := YahooPlayer{PlayerKey: "7", FullName: "Nobody Atall", PositionType: "B"}
+	if _, ok := r.Resolve(missing); ok {
+		t.Errorf("Expected an unknown player not to match")
+	}
+
+	unmatched := r.Unmatched()
+	if len(unmatched) != 2 {
+		t.Fatalf("Expected 2 unmatched players, got: %v", unmatched)
+	}
+	if unmatched[0].Player.FullName != "Chris Young" || len(unmatched[0].Candidates) != 2 {
+		t.Errorf("Expected both batting Chris Youngs as candidates, got: %v", unmatched[0])
+	}
+	if unmatched[1].Player.FullName != "Nobody Atall" || len(unmatched[1].Candidates) != 0 {
+		t.Errorf("Expected no candidates for Nobody Atall, got: %v", unmatched[1])
+	}
+
+	// Players without a key are each reported too.
+	r.Resolve(YahooPlayer{FullName: "Someone Else", PositionType: "B"})
+	r.Resolve(YahooPlayer{FullName: "Anybody Atall", PositionType: "B"})
+	if len(r.Unmatched()) != 4 {
+		t.Errorf("Expected 4 unmatched players, got: %v", r.Unmatched())
+	}
+
+	r.AddOverride(PlayerOverride{YahooKey: "6", ID: "Chris Young [Mets]"})
+	if id := r.PlayerID(ambiguous); id != "Chris Young [Mets]" {
+		t.Errorf("Expected the override to win, got: %s", id)
+	}
+	if len(r.Unmatched()) != 3 {
+		t.Errorf("Expected overriding a player to clear them from the unmatched list, got: %v", r.Unmatched())
+	}
+}
+
+func TestReadPlayerOverrides(t *testing.T) {
+	overrides, err := readPlayerOverrides(strings.NewReader(
+		"# yahoo_key,player_id,fangraphs_id,mlbam_id\n" +
+			"mlb.p.7578,Chris Young [Mets],3882\n" +
+			"mlb.p.8180,Giancarlo Stanton,4949,519317\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []PlayerOverride{
+		{YahooKey: "mlb.p.7578", ID: "Chris Young [Mets]", FanGraphsID: "3882"},
+		{YahooKey: "mlb.p.8180", ID: "Giancarlo Stanton", FanGraphsID: "4949", MLBAMID: "519317"},
+	}
+	if !reflect.DeepEqual(overrides, expected) {
+		t.Errorf("Expected %v, got: %v", expected, overrides)
+	}
+
+	_, err = readPlayerOverrides(strings.NewReader("mlb.p.1\n"))
+	if err == nil {
+		t.Errorf("Expected an error for a line without a player id")
+	}
+}
+
+func TestResolveRemembersMatches(t *testing.T) {
+	r := testResolver()
+
+	player := YahooPlayer{PlayerKey: "8", FullName: "Giancarlo Stantn", PositionType: "B"}
+	r.Resolve(player)
+	// Clear the records, so only a remembered match can find him.
+	r.byName = map[string][]int{}
+	if id := r.PlayerID(player); id != "Giancarlo Stanton" {
+		t.Errorf("Expected the match to be remembered, got: %s", id)
+	}
+
+	r.AddOverride(PlayerOverride{YahooKey: "8", ID: "Someone Else"})
+	if id := r.PlayerID(player); id != "Someone Else" {
+		t.Errorf("Expected an override to replace the remembered match, got: %s", id)
+	}
+}
+
+func TestWritePlayerOverrides(t *testing.T) {
+	r := testResolver()
+	r.AddOverride(PlayerOverride{YahooKey: "mlb.p.8180", ID: "Giancarlo Stanton", FanGraphsID: "4949"})
+	r.Resolve(YahooPlayer{PlayerKey: "mlb.p.6", FullName: "Chris Young", PositionType: "B"})
+
+	var out strings.Builder
+	if err := writePlayerOverrides(&out, r.Overrides(), r.Unmatched()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "#mlb.p.6,Chris Young [Mets],,\n") {
+		t.Errorf("Expected the candidates commented out, got:\n%s", out.String())
+	}
+
+	// Only the real overrides read back in.
+	overrides, err := readPlayerOverrides(strings.NewReader(out.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(overrides, r.Overrides()) {
+		t.Errorf("Expected %v, got: %v", r.Overrides(), overrides)
+	}
+}
@@ -0,0 +1,124 @@
+package folib
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// Yahoo doesn't publish its limits, but starts answering 999 when we
+	// send more than a few requests a second for long.
+	YAHOO_REQUESTS_PER_SECOND = 2
+	YAHOO_REQUEST_BURST       = 5
+)
+
+// Limits how often we make requests: each takes a token, and tokens come
+// back at a steady rate up to a burst.  It's safe to share between
+// goroutines, and a nil bucket doesn't limit anything.
+type TokenBucket struct {
+	rate  float64 // Tokens per second
+	burst float64
+	now   func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func NewTokenBucket(ratePerSecond float64, burst int) *TokenBucket {
+	if ratePerSecond <= 0 {
+		return nil
+	}
+	burst = max(burst, 1)
+	return &TokenBucket{
+		rate:   ratePerSecond,
+		burst:  float64(burst),
+		now:    time.Now,
+		tokens: float64(burst),
+	}
+}
+
+// Waits for a token, or until ctx is done.
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b == nil {
+		return nil
+	}
+
+	wait := b.reserve()
+	if wait <= 0 {
+		return nil
+	}
+	if err := sleepContext(ctx, wait); err != nil {
+		// We never used the token we were waiting for.
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Takes a token, returning how long to wait for it to be ours.  Waiters go
+// into debt, so they're served in the order they asked.
+func (b *TokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if !b.last.IsZero() {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// How many times to try a request Yahoo failed temporarily, and how long to
+// back off in between.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var DEFAULT_RETRY_POLICY = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    30 * time.Second,
+}
+
+// How long to wait before retrying, after the given number of failed
+// attempts.  The delay doubles each time, up to MaxDelay, and is picked at
+// random up to that, so clients that were throttled together don't all come
+// back at once.
+func (p RetryPolicy) backoff(failures int) time.Duration {
+	ceiling := p.MaxDelay
+	if failures < 32 && p.BaseDelay<<failures < ceiling && p.BaseDelay<<failures > 0 {
+		ceiling = p.BaseDelay << failures
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
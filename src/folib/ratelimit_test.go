@@ -0,0 +1,68 @@
+package folib
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Date(2014, 5, 1, 12, 0, 0, 0, time.UTC)
+	bucket := NewTokenBucket(2, 3)
+	bucket.now = func() time.Time { return now }
+
+	expected := []time.Duration{0, 0, 0, 500 * time.Millisecond, time.Second}
+	for i, wait := range expected {
+		if actual := bucket.reserve(); actual != wait {
+			t.Errorf("Request %d: expected to wait %v, got %v", i, wait, actual)
+		}
+	}
+
+	// Tokens come back at the rate, but no more than the burst.
+	now = now.Add(time.Minute)
+	for i := 0; i < 3; i++ {
+		if wait := bucket.reserve(); wait != 0 {
+			t.Errorf("Expected a full bucket, waited %v", wait)
+		}
+	}
+	if wait := bucket.reserve(); wait != 500*time.Millisecond {
+		t.Errorf("Expected to wait for the next token, got %v", wait)
+	}
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	bucket := NewTokenBucket(1, 1)
+	if err := bucket.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := bucket.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected to give up waiting, got: %v", err)
+	}
+	// The token we gave up on is still there for the next request.
+	if wait := bucket.reserve(); wait > time.Second {
+		t.Errorf("Expected to wait at most a second, got %v", wait)
+	}
+
+	var unlimited *TokenBucket
+	if err := unlimited.Wait(context.Background()); err != nil {
+		t.Errorf("Expected a nil bucket not to limit, got: %v", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	ceilings := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for failures, ceiling := range ceilings {
+		for i := 0; i < 100; i++ {
+			if delay := policy.backoff(failures); delay < 0 || delay > ceiling {
+				t.Errorf("After %d failures, expected at most %v, got %v", failures, ceiling, delay)
+			}
+		}
+	}
+	if delay := policy.backoff(100); delay > time.Second {
+		t.Errorf("Expected long runs of failures to be capped, got %v", delay)
+	}
+}
@@ -0,0 +1,143 @@
+package folib
+
+import (
+	"sort"
+)
+
+// How many players a team may carry at each position, as configured by the
+// league.  Only the Starters slots contribute to a team's stats.
+type RosterTopology struct {
+	Starters map[Position]int
+	Bench    int
+	Injured  int
+	Minors   int
+}
+
+const (
+	BENCH  Position = "BN"
+	MINORS Position = "NA"
+)
+
+// Slots which don't count towards a team's stats.  Yahoo has called the
+// injured list both "DL" and "IL" over the years.
+var inactivePositions = map[Position]bool{
+	BENCH:  true,
+	MINORS: true,
+	"DL":   true,
+	"IL":   true,
+	"IL+":  true,
+}
+
+// Slots which can be filled by players eligible at any of several positions.
+// "Util" and "P" are handled by position type, rather than listed here.
+var compositePositions = map[Position][]Position{
+	"CI": []Position{"1B", "3B"},
+	"MI": []Position{"2B", "SS"},
+	"IF": []Position{"1B", "2B", "3B", "SS"},
+	"OF": []Position{"LF", "CF", "RF"},
+}
+
+func NewRosterTopology(positions []YahooRosterPosition) RosterTopology {
+	topology := RosterTopology{Starters: make(map[Position]int)}
+	for _, p := range positions {
+		pos := Position(p.Position)
+		switch {
+		case pos == BENCH:
+			topology.Bench += p.Count
+		case pos == MINORS:
+			topology.Minors += p.Count
+		case inactivePositions[pos]:
+			topology.Injured += p.Count
+		default:
+			topology.Starters[pos] += p.Count
+		}
+	}
+	return topology
+}
+
+// The total number of players who can be in the starting lineup.
+func (t RosterTopology) StartingSlots() int {
+	n := 0
+	for _, count := range t.Starters {
+		n += count
+	}
+	return n
+}
+
+// The total number of active (non-injured, non-minors) players a team can
+// carry.
+func (t RosterTopology) ActiveSize() int {
+	return t.StartingSlots() + t.Bench
+}
+
+// The starting positions, in a stable order.
+func (t RosterTopology) positions() []Position {
+	positions := make([]Position, 0, len(t.Starters))
+	for pos := range t.Starters {
+		positions = append(positions, pos)
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i] < positions[j] })
+	return positions
+}
+
+// The starting slots this player could fill.  Positions the player is listed
+// at come first (in Yahoo's order), followed by any flex slots.
+func (t RosterTopology) eligibleSlots(player YahooPlayer) []Position {
+	slots := []Position{}
+	if isInactive(player) {
+		return slots
+	}
+
+	seen := map[Position]bool{}
+	for _, posStr := range player.Position {
+		pos := Position(posStr)
+		if t.Starters[pos] > 0 && !seen[pos] {
+			slots = append(slots, pos)
+			seen[pos] = true
+		}
+	}
+	for _, pos := range t.positions() {
+		if !seen[pos] && canPlay(player, pos) {
+			slots = append(slots, pos)
+			seen[pos] = true
+		}
+	}
+	return slots
+}
+
+func canPlay(player YahooPlayer, slot Position) bool {
+	if isInactive(player) {
+		return false
+	}
+
+	switch slot {
+	case "Util":
+		return player.PositionType == "B"
+	case "P":
+		return player.PositionType == "P"
+	}
+
+	for _, posStr := range player.Position {
+		pos := Position(posStr)
+		if pos == slot {
+			return true
+		}
+		for _, component := range compositePositions[slot] {
+			if pos == component {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Injured and minor-league players can't be put into the starting lineup.
+func isInactive(player YahooPlayer) bool {
+	for _, posStr := range player.Position {
+		pos := Position(posStr)
+		if pos != BENCH && inactivePositions[pos] {
+			return true
+		}
+	}
+	return false
+}
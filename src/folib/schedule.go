@@ -0,0 +1,289 @@
+package folib
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	SEASON_GAMES = 162
+	// How many starts a starting pitcher makes, if their projection
+	// doesn't say.
+	SEASON_STARTS = 32
+	// Used to guess at starts for games without a probable pitcher.
+	ROTATION_SIZE = 5
+)
+
+func mlbScheduleUrl(season int) string {
+	return fmt.Sprintf("https://statsapi.mlb.com/api/v1/schedule?sportId=1&season=%d&gameType=R&hydrate=team,probablePitcher", season)
+}
+
+// One game on the MLB schedule.  Teams are standard abbreviations (see
+// normalizeTeam), and probable pitchers are names, or empty if not yet
+// announced.
+type ScheduledGame struct {
+	Date         string // e.g. "2014-05-01"
+	Away         string
+	Home         string
+	AwayProbable string
+	HomeProbable string
+}
+
+// The MLB schedule, which tells us how much each player will play over a
+// given stretch of days.
+type Schedule struct {
+	byDate map[string][]ScheduledGame
+}
+
+func NewSchedule(games []ScheduledGame) *Schedule {
+	schedule := &Schedule{byDate: make(map[string][]ScheduledGame)}
+	for _, game := range games {
+		schedule.byDate[game.Date] = append(schedule.byDate[game.Date], game)
+	}
+	return schedule
+}
+
+// Loads a schedule from a CSV file with "date", "away" and "home" columns,
+// and optionally "away_probable" and "home_probable".
+func LoadSchedule(filename string) (*Schedule, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readSchedule(f)
+}
+
+// Fetches a season's schedule from MLB.  Probable pitchers are announced
+// a few days ahead, so it's only cached for a day.
+func FetchSchedule(season int) (*Schedule, error) {
+	cache := NewReadThroughCache(NewFileKVStore("./cache"))
+	body, err := cache.Get(
+		urlFetcher(mlbScheduleUrl(season)),
+		fmt.Sprintf("mlb_schedule_%d.json", season),
+		ONE_DAY)
+	if err != nil {
+		return nil, err
+	}
+	return parseMlbSchedule(body)
+}
+
+func readSchedule(f io.Reader) (*Schedule, error) {
+	recs, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(recs) == 0 {
+		return nil, fmt.Errorf("Empty schedule")
+	}
+
+	header := recs[0]
+	dateIndex := findColumn(header, "date")
+	awayIndex := findColumn(header, "away")
+	homeIndex := findColumn(header, "home")
+	if dateIndex == -1 || awayIndex == -1 || homeIndex == -1 {
+		return nil, fmt.Errorf("Schedule needs date, away and home columns, got: %v", header)
+	}
+	awayProbableIndex := findColumn(header, "away_probable")
+	homeProbableIndex := findColumn(header, "home_probable")
+
+	games := []ScheduledGame{}
+	for _, rec := range recs[1:] {
+		game := ScheduledGame{
+			Date: rec[dateIndex],
+			Away: normalizeTeam(rec[awayIndex]),
+			Home: normalizeTeam(rec[homeIndex]),
+		}
+		if game.Away == "" || game.Home == "" {
+			return nil, fmt.Errorf("Unknown team in schedule: %v", rec)
+		}
+		if awayProbableIndex != -1 {
+			game.AwayProbable = rec[awayProbableIndex]
+		}
+		if homeProbableIndex != -1 {
+			game.HomeProbable = rec[homeProbableIndex]
+		}
+		games = append(games, game)
+	}
+	return NewSchedule(games), nil
+}
+
+type mlbSchedule struct {
+	Dates []struct {
+		Date  string `json:"date"`
+		Games []struct {
+			Status struct {
+				DetailedState string `json:"detailedState"`
+			} `json:"status"`
+			Teams struct {
+				Away mlbScheduleTeam `json:"away"`
+				Home mlbScheduleTeam `json:"home"`
+			} `json:"teams"`
+		} `json:"games"`
+	} `json:"dates"`
+}
+
+type mlbScheduleTeam struct {
+	Team struct {
+		Abbreviation string `json:"abbreviation"`
+	} `json:"team"`
+	ProbablePitcher struct {
+		FullName string `json:"fullName"`
+	} `json:"probablePitcher"`
+}
+
+func parseMlbSchedule(body string) (*Schedule, error) {
+	var data mlbSchedule
+	err := json.Unmarshal([]byte(body), &data)
+	if err != nil {
+		return nil, err
+	}
+
+	games := []ScheduledGame{}
+	for _, date := range data.Dates {
+		for _, g := range date.Games {
+			// Postponed games show up again on the day they're made up.
+			if g.Status.DetailedState == "Postponed" || g.Status.DetailedState == "Cancelled" {
+				continue
+			}
+			game := ScheduledGame{
+				Date:         date.Date,
+				Away:         normalizeTeam(g.Teams.Away.Team.Abbreviation),
+				Home:         normalizeTeam(g.Teams.Home.Team.Abbreviation),
+				AwayProbable: g.Teams.Away.ProbablePitcher.FullName,
+				HomeProbable: g.Teams.Home.ProbablePitcher.FullName,
+			}
+			if game.Away == "" || game.Home == "" {
+				// e.g. exhibitions against non-MLB teams.
+				continue
+			}
+			games = append(games, game)
+		}
+	}
+	return NewSchedule(games), nil
+}
+
+// Calls f for each of a team's games from start to end (inclusive), with
+// the game's date and that team's probable pitcher for it.
+func (s *Schedule) eachGame(team string, start, end time.Time, f func(date, probable string)) {
+	for day := start; !day.After(end); day = day.Add(ONE_DAY) {
+		date := day.Format("2006-01-02")
+		for _, game := range s.byDate[date] {
+			switch team {
+			case game.Away:
+				f(date, game.AwayProbable)
+			case game.Home:
+				f(date, game.HomeProbable)
+			}
+		}
+	}
+}
+
+// How many games a team (e.g. "NYY") plays from start to end, inclusive.
+func (s *Schedule) Games(team string, start, end time.Time) int {
+	games := 0
+	s.eachGame(normalizeTeam(team), start, end, func(string, string) { games++ })
+	return games
+}
+
+// The dates from start to end (inclusive) that a pitcher is announced as the
+// probable starter.
+func (s *Schedule) ProbableStarts(pitcher, team string, start, end time.Time) []string {
+	name := normalizeName(pitcher)
+	dates := []string{}
+	s.eachGame(normalizeTeam(team), start, end, func(date, probable string) {
+		if probable != "" && normalizeName(probable) == name {
+			dates = append(dates, date)
+		}
+	})
+	return dates
+}
+
+// How many starts we expect from a pitcher from start to end, inclusive:
+// one for each game they're the probable starter for, and a share of the
+// games without an announced starter.
+func (s *Schedule) ExpectedStarts(pitcher, team string, start, end time.Time) float64 {
+	name := normalizeName(pitcher)
+	starts := float64(0)
+	s.eachGame(normalizeTeam(team), start, end, func(_, probable string) {
+		switch {
+		case probable == "":
+			starts += 1.0 / ROTATION_SIZE
+		case normalizeName(probable) == name:
+			starts++
+		}
+	})
+	return starts
+}
+
+// The share of the season's days from start to end, inclusive.  The season
+// lasts as long as the league's, if we know it.
+func (fo *FO) seasonShare(start, end time.Time) float64 {
+	days := max(int(end.Sub(start)/ONE_DAY)+1, 0)
+	seasonDays := SEASON_DAYS
+	if fo.settings != nil && !fo.settings.Season.IsZero() {
+		seasonDays = fo.settings.Season.Days()
+	}
+	return float64(days) / float64(seasonDays)
+}
+
+// What share of a player's season projection they should accumulate from
+// start to end, inclusive.  Without a schedule, or for players we can't
+// place on a team, this is just the share of the season's days.
+func (fo *FO) projectionShare(player YahooPlayer, line StatLine, start, end time.Time) float64 {
+	team := normalizeTeam(player.TeamAbbr)
+	if fo.schedule == nil || team == "" || end.Before(start) {
+		return fo.seasonShare(start, end)
+	}
+
+	if isStartingPitcher(player) {
+		starts := float64(line[P_STARTS])
+		if starts <= 0 {
+			starts = SEASON_STARTS
+		}
+		return fo.schedule.ExpectedStarts(player.FullName, team, start, end) / starts
+	}
+	// Projections already account for how much everyone else plays, so we
+	// only need to know how many games their team has.
+	return float64(fo.schedule.Games(team, start, end)) / SEASON_GAMES
+}
+
+// Projects each player over the days from start to end, inclusive.
+func (fo *FO) projectPlayersBetween(players []YahooPlayer, start, end time.Time) map[PlayerID]StatLine {
+	statMap := make(map[PlayerID]StatLine)
+	for _, player := range players {
+		line := fo.projections.GetStatLine(fo.playerID(player))
+		statMap[fo.playerID(player)] = scaleStatLine(line, fo.projectionShare(player, line, start, end))
+	}
+	return statMap
+}
+
+// Projects the combined stats of the starters a roster would use from
+// start to end, inclusive, picking them by what they'll do over that
+// stretch rather than over the season, so that players with more games
+// (or starts) come out ahead.
+func (fo *FO) projectStartersBetween(roster []YahooPlayer, start, end time.Time) StatLine {
+	if fo.schedule == nil {
+		return fo.projectStarters(roster, fo.seasonShare(start, end))
+	}
+
+	statMap := fo.projectPlayersBetween(roster, start, end)
+	scores := scoreTeam(statMap, fo.settings.Scorer())
+
+	values := make([]float32, len(roster))
+	for i, player := range roster {
+		values[i] = scores[fo.playerID(player)]
+	}
+
+	lines := []StatLine{}
+	for _, indices := range optimalLineup(roster, values, fo.settings.Topology) {
+		for _, i := range indices {
+			lines = append(lines, statMap[fo.playerID(roster[i])])
+		}
+	}
+	return merge(lines)
+}
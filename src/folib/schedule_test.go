@@ -0,0 +1,152 @@
+package folib
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func day(date string) time.Time {
+	t, _ := time.Parse("2006-01-02", date)
+	return t
+}
+
+const scheduleCsv = `date,away,home,away_probable,home_probable
+2014-05-01,NYY,BOS,Masahiro Tanaka,
+2014-05-02,Yankees,Red Sox,,Jon Lester
+2014-05-02,NYY,BOS,,
+2014-05-03,DET,BOS,,
+`
+
+func TestReadSchedule(t *testing.T) {
+	schedule, err := readSchedule(strings.NewReader(scheduleCsv))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if games := schedule.Games("Bos", day("2014-05-01"), day("2014-05-03")); games != 4 {
+		t.Errorf("Expected Boston to play 4 games, got %d", games)
+	}
+	if games := schedule.Games("NYY", day("2014-05-03"), day("2014-05-04")); games != 0 {
+		t.Errorf("Expected the Yankees to be off, got %d games", games)
+	}
+
+	// Tanaka starts once, and might start either game of the doubleheader.
+	starts := schedule.ExpectedStarts("Masahiro Tanaka", "NYY", day("2014-05-01"), day("2014-05-03"))
+	if math.Abs(starts-(1+2.0/ROTATION_SIZE)) > 1e-9 {
+		t.Errorf("Expected %f starts for Tanaka, got %f", 1+2.0/ROTATION_SIZE, starts)
+	}
+	// Lester's start is announced, so nobody else gets it.
+	starts = schedule.ExpectedStarts("Clay Buchholz", "BOS", day("2014-05-02"), day("2014-05-02"))
+	if math.Abs(starts-1.0/ROTATION_SIZE) > 1e-9 {
+		t.Errorf("Expected only the second game to be open, got %f starts", starts)
+	}
+
+	_, err = readSchedule(strings.NewReader("date,away,home\n2014-05-01,NYY,Nowhere\n"))
+	if err == nil {
+		t.Errorf("Expected an error for an unknown team")
+	}
+}
+
+const mlbScheduleJson = `{
+  "dates": [{
+    "date": "2014-05-01",
+    "games": [{
+      "status": {"detailedState": "Scheduled"},
+      "teams": {
+        "away": {"team": {"abbreviation": "NYY"}, "probablePitcher": {"fullName": "Masahiro Tanaka"}},
+        "home": {"team": {"abbreviation": "BOS"}}
+      }
+    }, {
+      "status": {"detailedState": "Postponed"},
+      "teams": {
+        "away": {"team": {"abbreviation": "DET"}},
+        "home": {"team": {"abbreviation": "CWS"}}
+      }
+    }]
+  }]
+}`
+
+func TestParseMlbSchedule(t *testing.T) {
+	schedule, err := parseMlbSchedule(mlbScheduleJson)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if games := schedule.Games("BOS", day("2014-05-01"), day("2014-05-01")); games != 1 {
+		t.Errorf("Expected Boston to play once, got %d", games)
+	}
+	if games := schedule.Games("DET", day("2014-05-01"), day("2014-05-01")); games != 0 {
+		t.Errorf("Expected the postponed game to be skipped, got %d", games)
+	}
+	if starts := schedule.ExpectedStarts("Masahiro Tanaka", "NYY", day("2014-05-01"), day("2014-05-01")); starts != 1 {
+		t.Errorf("Expected Tanaka to start, got %f", starts)
+	}
+}
+
+func TestProjectStartersBetween(t *testing.T) {
+	// The Yankees play every day, and Detroit only plays four times.
+	games := []ScheduledGame{}
+	for d := day("2014-04-28"); !d.After(day("2014-05-04")); d = d.Add(ONE_DAY) {
+		games = append(games, ScheduledGame{Date: d.Format("2006-01-02"), Away: "NYY", Home: "BOS"})
+		if d.Weekday() >= time.Thursday || d.Weekday() == time.Sunday {
+			games = append(games, ScheduledGame{Date: d.Format("2006-01-02"), Away: "DET", Home: "CWS"})
+		}
+	}
+
+	fo := testFO(fakeStatsClient{
+		"Every Day": {B_HOME_RUNS: 30},
+		"Four Days": {B_HOME_RUNS: 40},
+	}, &LeagueSettings{
+		Categories: ScoringCategories{B_HOME_RUNS: HIGHER_IS_BETTER},
+		Topology:   RosterTopology{Starters: map[Position]int{"OF": 1}},
+	})
+	fo.schedule = NewSchedule(games)
+
+	roster := []YahooPlayer{
+		{FullName: "Every Day", PositionType: "B", Position: []string{"OF"}, TeamAbbr: "NYY"},
+		{FullName: "Four Days", PositionType: "B", Position: []string{"OF"}, TeamAbbr: "Det"},
+	}
+
+	line := fo.projectStartersBetween(roster, day("2014-04-28"), day("2014-05-04"))
+	expected := 30.0 * 7 / SEASON_GAMES
+	if math.Abs(float64(line[B_HOME_RUNS])-expected) > 1e-6 {
+		t.Errorf("Expected the everyday player to start, for %f home runs, got %f", expected, line[B_HOME_RUNS])
+	}
+
+	// Off days and unannounced starts are filled in from the schedule.
+	roster = append(roster,
+		YahooPlayer{FullName: "Ace", PositionType: "P", Position: []string{"SP"}, TeamAbbr: "NYY"})
+	statuses := fo.scheduledStatuses(roster, "2014-04-29", []DailyStatus{MAYBE_PLAYING, MAYBE_PLAYING, MAYBE_PLAYING})
+	if statuses[0] != MAYBE_PLAYING || statuses[1] != OFF_DAY || statuses[2] != MAYBE_PLAYING {
+		t.Errorf("Expected only Four Days to be off, got %v", statuses)
+	}
+}
+
+func TestProjectStartersBetweenWithoutSchedule(t *testing.T) {
+	season, err := NewSeasonCalendar("2014-04-01", "2014-07-09")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fo := testFO(fakeStatsClient{"Every Day": {B_HOME_RUNS: 30}}, &LeagueSettings{
+		Categories: ScoringCategories{B_HOME_RUNS: HIGHER_IS_BETTER},
+		Topology:   RosterTopology{Starters: map[Position]int{"OF": 1}},
+		Season:     season,
+	})
+	roster := []YahooPlayer{
+		{FullName: "Every Day", PositionType: "B", Position: []string{"OF"}, TeamAbbr: "NYY"},
+	}
+
+	// A week of the league's 100 day season.
+	line := fo.projectStartersBetween(roster, day("2014-04-28"), day("2014-05-04"))
+	if expected := 30.0 * 7 / 100; math.Abs(float64(line[B_HOME_RUNS])-expected) > 1e-6 {
+		t.Errorf("Expected %f home runs, got %f", expected, line[B_HOME_RUNS])
+	}
+
+	fo.settings.Season = SeasonCalendar{}
+	line = fo.projectStartersBetween(roster, day("2014-04-28"), day("2014-05-04"))
+	if expected := 30.0 * 7 / SEASON_DAYS; math.Abs(float64(line[B_HOME_RUNS])-expected) > 1e-6 {
+		t.Errorf("Expected %f home runs over a typical season, got %f", expected, line[B_HOME_RUNS])
+	}
+}
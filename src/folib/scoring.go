@@ -5,13 +5,19 @@ import (
 	"strconv"
 )
 
-func scoreLeague(stats map[TeamID]StatLine, scoringCategories map[StatID]struct{}) map[TeamID]float32 {
+// Turns a set of stat lines (for teams, or for players) into one score per
+// line, where a higher score is better.
+type Scorer interface {
+	Score(stats map[string]StatLine) map[string]float32
+}
+
+func scoreLeague(stats map[TeamID]StatLine, scorer Scorer) map[TeamID]float32 {
 	rawStats := make(map[string]StatLine)
 	for k, v := range stats {
 		rawStats[strconv.Itoa(int(k))] = v
 	}
 
-	rawScores := score(rawStats, scoringCategories)
+	rawScores := scorer.Score(rawStats)
 	scores := make(map[TeamID]float32)
 	for k, v := range rawScores {
 		i, err := strconv.Atoi(k)
@@ -24,13 +30,13 @@ func scoreLeague(stats map[TeamID]StatLine, scoringCategories map[StatID]struct{
 	return scores
 }
 
-func scoreTeam(stats map[PlayerID]StatLine, scoringCategories map[StatID]struct{}) map[PlayerID]float32 {
+func scoreTeam(stats map[PlayerID]StatLine, scorer Scorer) map[PlayerID]float32 {
 	rawStats := make(map[string]StatLine)
 	for k, v := range stats {
 		rawStats[string(k)] = v
 	}
 
-	rawScores := score(rawStats, scoringCategories)
+	rawScores := scorer.Score(rawStats)
 	scores := make(map[PlayerID]float32)
 	for k, v := range rawScores {
 		scores[PlayerID(k)] = v
@@ -39,17 +45,35 @@ func scoreTeam(stats map[PlayerID]StatLine, scoringCategories map[StatID]struct{
 	return scores
 }
 
-func score(stats map[string]StatLine, scoringCategories map[StatID]struct{}) map[string]float32 {
+// Rotisserie scoring: each line gets points for how it ranks in each
+// category.
+func (categories ScoringCategories) Score(stats map[string]StatLine) map[string]float32 {
 	scoresByStat := make(map[StatID]map[string]float32)
 
-	for statid := range scoringCategories {
-		scoresByStat[statid] = scoreStat(stats, statid)
+	for statid, order := range categories {
+		scoresByStat[statid] = scoreStat(stats, statid, order)
 	}
 
 	return flatten(scoresByStat)
 }
 
-func scoreStat(stats map[string]StatLine, statid StatID) map[string]float32 {
+// Points scoring: every unit of a stat is worth a fixed number of points,
+// e.g. {B_HOME_RUNS: 4, P_EARNED_RUNS: -2}.
+type PointWeights map[StatID]float32
+
+func (weights PointWeights) Score(stats map[string]StatLine) map[string]float32 {
+	scores := make(map[string]float32)
+	for id, statline := range stats {
+		total := float32(0)
+		for statid, weight := range weights {
+			total += weight * float32(statline[statid])
+		}
+		scores[id] = total
+	}
+	return scores
+}
+
+func scoreStat(stats map[string]StatLine, statid StatID, order SortOrder) map[string]float32 {
 	numteams := len(stats)
 	scoremap := make(map[string]float32)
 
@@ -64,7 +88,7 @@ func scoreStat(stats map[string]StatLine, statid StatID) map[string]float32 {
 		if idx < numteams-1 && slice[idx] == slice[idx+1] {
 			score += .5
 		}
-		if lowerIsBetter(statid) {
+		if order == LOWER_IS_BETTER {
 			score = float32(numteams) - score + 1
 		}
 		scoremap[teamid] = score
@@ -10,7 +10,7 @@ func TestTwoTeamsOneStat(t *testing.T) {
 		2: StatLine{B_HOME_RUNS: 5},
 	}
 
-	score := scoreLeague(stats, map[StatID]struct{}{B_HOME_RUNS: struct{}{}})
+	score := scoreLeague(stats, ScoringCategories{B_HOME_RUNS: HIGHER_IS_BETTER})
 
 	if score[1] != 2 {
 		t.Errorf("Team 1 should have 2 points, has: %f", score[1])
@@ -26,9 +26,9 @@ func TestTwoTeamsTwoStats(t *testing.T) {
 		2: StatLine{B_HOME_RUNS: 5, P_STRIKE_OUTS: 50},
 	}
 
-	score := scoreLeague(stats, map[StatID]struct{}{
-		B_HOME_RUNS:   struct{}{},
-		P_STRIKE_OUTS: struct{}{},
+	score := scoreLeague(stats, ScoringCategories{
+		B_HOME_RUNS:   HIGHER_IS_BETTER,
+		P_STRIKE_OUTS: HIGHER_IS_BETTER,
 	})
 
 	if score[1] != 4 {
@@ -45,9 +45,9 @@ func TestTwoTeamsReverseStats(t *testing.T) {
 		2: StatLine{B_HOME_RUNS: 5, P_EARNED_RUN_AVERAGE: 5.00},
 	}
 
-	score := scoreLeague(stats, map[StatID]struct{}{
-		B_HOME_RUNS:          struct{}{},
-		P_EARNED_RUN_AVERAGE: struct{}{},
+	score := scoreLeague(stats, ScoringCategories{
+		B_HOME_RUNS:          HIGHER_IS_BETTER,
+		P_EARNED_RUN_AVERAGE: LOWER_IS_BETTER,
 	})
 
 	if score[1] != 4 {
@@ -64,8 +64,8 @@ func TestIgnoresNonCountingStats(t *testing.T) {
 		2: StatLine{B_HOME_RUNS: 5, B_AT_BATS: 500},
 	}
 
-	score := scoreLeague(stats, map[StatID]struct{}{
-		B_HOME_RUNS: struct{}{},
+	score := scoreLeague(stats, ScoringCategories{
+		B_HOME_RUNS: HIGHER_IS_BETTER,
 	})
 
 	if score[1] != 2 {
@@ -82,8 +82,8 @@ func TestHandlesTies(t *testing.T) {
 		2: StatLine{B_HOME_RUNS: 5},
 	}
 
-	score := scoreLeague(stats, map[StatID]struct{}{
-		B_HOME_RUNS: struct{}{},
+	score := scoreLeague(stats, ScoringCategories{
+		B_HOME_RUNS: HIGHER_IS_BETTER,
 	})
 
 	if score[1] != 1.5 {
@@ -93,3 +93,24 @@ func TestHandlesTies(t *testing.T) {
 		t.Errorf("Team 2 should have 1 points, has: %f", score[2])
 	}
 }
+
+func TestPointsScoring(t *testing.T) {
+	stats := map[TeamID]StatLine{
+		1: StatLine{B_HOME_RUNS: 10, B_STOLEN_BASES: 1, P_INNINGS: 10, P_EARNED_RUNS: 5},
+		2: StatLine{B_HOME_RUNS: 5, B_STOLEN_BASES: 20, P_INNINGS: 20, P_EARNED_RUNS: 10},
+	}
+
+	score := scoreLeague(stats, PointWeights{
+		B_HOME_RUNS:    4,
+		B_STOLEN_BASES: 2,
+		P_INNINGS:      3,
+		P_EARNED_RUNS:  -2,
+	})
+
+	if score[1] != 62 {
+		t.Errorf("Team 1 should have 62 points, has: %f", score[1])
+	}
+	if score[2] != 100 {
+		t.Errorf("Team 2 should have 100 points, has: %f", score[2])
+	}
+}
@@ -0,0 +1,142 @@
+package folib
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// The first and last days of a fantasy season.
+type SeasonCalendar struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Parses a season from Yahoo-style dates, e.g. "2014-03-30".
+func NewSeasonCalendar(start, end string) (SeasonCalendar, error) {
+	s, err := time.Parse("2006-01-02", start)
+	if err != nil {
+		return SeasonCalendar{}, err
+	}
+	e, err := time.Parse("2006-01-02", end)
+	if err != nil {
+		return SeasonCalendar{}, err
+	}
+	if e.Before(s) {
+		return SeasonCalendar{}, fmt.Errorf("Season ends (%s) before it starts (%s)", end, start)
+	}
+	return SeasonCalendar{Start: s, End: e}, nil
+}
+
+func (c SeasonCalendar) IsZero() bool {
+	return c.Start.IsZero() || c.End.IsZero()
+}
+
+// How many days the season lasts, counting both the first and last day.
+func (c SeasonCalendar) Days() int {
+	return int(c.End.Sub(c.Start)/ONE_DAY) + 1
+}
+
+// How much of the season (0 to 1) has been played before the given day.
+// Games today haven't happened yet, so on opening day nothing is complete.
+func (c SeasonCalendar) Complete(now time.Time) float64 {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	played := int(today.Sub(c.Start) / ONE_DAY)
+	switch {
+	case played <= 0:
+		return 0
+	case played >= c.Days():
+		return 1
+	}
+	return float64(played) / float64(c.Days())
+}
+
+type SeasonProjection struct {
+	// How much of the season had been played when we projected it.
+	SeasonComplete float64
+
+	Actual map[TeamID]StatLine // Stats so far
+	Rest   map[TeamID]StatLine // Projected stats for the rest of the season
+	Final  map[TeamID]StatLine
+
+	Scores map[TeamID]float32
+}
+
+// Teams ordered by their projected final score, best first.
+func (p *SeasonProjection) Standings() []TeamID {
+	teams := make([]TeamID, 0, len(p.Scores))
+	for team := range p.Scores {
+		teams = append(teams, team)
+	}
+	sort.Slice(teams, func(i, j int) bool {
+		if p.Scores[teams[i]] != p.Scores[teams[j]] {
+			return p.Scores[teams[i]] > p.Scores[teams[j]]
+		}
+		return teams[i] < teams[j]
+	})
+	return teams
+}
+
+// Projects the final standings, by adding each team's starters' projections
+// for the rest of the season to what the team has done so far.
+func (fo *FO) SimulateSeason(ctx context.Context, now time.Time) (*SeasonProjection, error) {
+	actual, rosters, complete, err := fo.seasonSoFar(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	rest := make(map[TeamID]StatLine)
+	for team, roster := range rosters {
+		rest[team] = fo.projectRoster(roster, complete)
+	}
+
+	projection := projectSeason(actual, rest, fo.settings.Scorer())
+	projection.SeasonComplete = complete
+	return projection, nil
+}
+
+// Each team's stats and roster as of now, and how much of the season (0 to
+// 1) they've played.
+func (fo *FO) seasonSoFar(ctx context.Context, now time.Time) (map[TeamID]StatLine, map[TeamID][]YahooPlayer, float64, error) {
+	err := fo.loadSettings(ctx)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if fo.settings.Season.IsZero() {
+		return nil, nil, 0, fmt.Errorf("League %s has no season dates", fo.league.LeagueKey)
+	}
+
+	actual, err := fo.yahoo.CurrentStats(ctx, fo.league.LeagueKey)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	rosters, err := fo.yahoo.LeagueRosters(ctx, fo.league.LeagueKey)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	return *actual, *rosters, fo.settings.Season.Complete(now), nil
+}
+
+func projectSeason(actual, rest map[TeamID]StatLine, scorer Scorer) *SeasonProjection {
+	final := make(map[TeamID]StatLine)
+	for team, line := range rest {
+		final[team] = merge([]StatLine{actual[team], line})
+	}
+	// Teams with stats but no roster (e.g. an abandoned team) keep what
+	// they've got.
+	for team, line := range actual {
+		if _, ok := final[team]; !ok {
+			final[team] = merge([]StatLine{line})
+		}
+	}
+
+	return &SeasonProjection{
+		Actual: actual,
+		Rest:   rest,
+		Final:  final,
+		Scores: scoreLeague(final, scorer),
+	}
+}
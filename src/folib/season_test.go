@@ -0,0 +1,86 @@
+package folib
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSeasonCalendar(t *testing.T) {
+	season, err := NewSeasonCalendar("2014-03-30", "2014-09-28")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if season.Days() != 183 {
+		t.Errorf("Expected 183 days, got: %d", season.Days())
+	}
+
+	cases := []struct {
+		now      time.Time
+		expected float64
+	}{
+		{time.Date(2014, 2, 1, 12, 0, 0, 0, time.UTC), 0},
+		{time.Date(2014, 3, 30, 23, 0, 0, 0, time.UTC), 0},
+		{time.Date(2014, 3, 31, 1, 0, 0, 0, time.UTC), 1.0 / 183},
+		{time.Date(2014, 6, 29, 12, 0, 0, 0, time.UTC), 91.0 / 183},
+		{time.Date(2014, 10, 15, 12, 0, 0, 0, time.UTC), 1},
+	}
+	for _, c := range cases {
+		if actual := season.Complete(c.now); actual != c.expected {
+			t.Errorf("%s: expected %f complete, got %f", c.now, c.expected, actual)
+		}
+	}
+
+	if _, err := NewSeasonCalendar("2014-09-28", "2014-03-30"); err == nil {
+		t.Errorf("Expected an error for a season that ends before it starts")
+	}
+}
+
+func TestProjectSeason(t *testing.T) {
+	categories := ScoringCategories{
+		B_HOME_RUNS:          HIGHER_IS_BETTER,
+		P_EARNED_RUN_AVERAGE: LOWER_IS_BETTER,
+	}
+
+	// Team 1 is ahead so far, but team 2 is projected to catch up.
+	actual := map[TeamID]StatLine{
+		1: {B_HOME_RUNS: 100, P_INNINGS: 600, P_EARNED_RUN_AVERAGE: 3.00},
+		2: {B_HOME_RUNS: 90, P_INNINGS: 600, P_EARNED_RUN_AVERAGE: 3.30},
+	}
+	rest := map[TeamID]StatLine{
+		1: {B_HOME_RUNS: 50, P_EARNED_RUNS: 180, P_INNINGS: 300, P_EARNED_RUN_AVERAGE: 5.40},
+		2: {B_HOME_RUNS: 70, P_EARNED_RUNS: 60, P_INNINGS: 300, P_EARNED_RUN_AVERAGE: 1.80},
+	}
+
+	projection := projectSeason(actual, rest, categories)
+
+	if projection.Final[1][B_HOME_RUNS] != 150 || projection.Final[2][B_HOME_RUNS] != 160 {
+		t.Errorf("Expected 150 and 160 HR, got: %v", projection.Final)
+	}
+	// (200 + 180) ER in 900 IP, and (220 + 60) ER in 900 IP.
+	if !closeEnough(projection.Final[1][P_EARNED_RUN_AVERAGE], 3.80) {
+		t.Errorf("Expected team 1 to finish with a 3.80 ERA, got: %f", projection.Final[1][P_EARNED_RUN_AVERAGE])
+	}
+	if !closeEnough(projection.Final[2][P_EARNED_RUN_AVERAGE], 2.80) {
+		t.Errorf("Expected team 2 to finish with a 2.80 ERA, got: %f", projection.Final[2][P_EARNED_RUN_AVERAGE])
+	}
+
+	if !reflect.DeepEqual(projection.Standings(), []TeamID{2, 1}) {
+		t.Errorf("Expected team 2 to win, got: %v (%v)", projection.Standings(), projection.Scores)
+	}
+}
+
+func TestProjectSeasonKeepsTeamsWithoutRosters(t *testing.T) {
+	actual := map[TeamID]StatLine{
+		1: {B_HOME_RUNS: 10},
+		2: {B_HOME_RUNS: 20},
+	}
+	rest := map[TeamID]StatLine{
+		1: {B_HOME_RUNS: 30},
+	}
+
+	projection := projectSeason(actual, rest, ScoringCategories{B_HOME_RUNS: HIGHER_IS_BETTER})
+	if projection.Final[1][B_HOME_RUNS] != 40 || projection.Final[2][B_HOME_RUNS] != 20 {
+		t.Errorf("Expected 40 and 20 HR, got: %v", projection.Final)
+	}
+}
@@ -0,0 +1,271 @@
+package folib
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	BATTING  = "B"
+	PITCHING = "P"
+)
+
+// Everything we know about a stat: what to call it, how to score and display
+// it, and what each of our data sources calls it.
+type StatInfo struct {
+	ID   StatID
+	Name string // Short name, e.g. "HR"
+	Side string // BATTING or PITCHING (matching Yahoo's position_type)
+
+	// Which direction is better, for leagues that don't say otherwise.
+	Order SortOrder
+	// How to compute the stat from counting stats.  nil for counting stats.
+	Rate *rateFormula
+	// How to display a value of this stat, e.g. "%.3f".
+	Format string
+
+	// What each source calls this stat.  Zero values mean the source doesn't
+	// have it.
+	YahooID         int
+	ZipsColumn      ColName
+	FanGraphsColumn ColName
+}
+
+func (info StatInfo) IsRate() bool {
+	return info.Rate != nil
+}
+
+const (
+	COUNTING_FORMAT = "%.0f"
+	AVERAGE_FORMAT  = "%.3f"
+	ERA_FORMAT      = "%.2f"
+)
+
+var statRegistry = []StatInfo{
+	{ID: B_AT_BATS, Name: "AB", Side: BATTING, Format: COUNTING_FORMAT,
+		YahooID: 6, ZipsColumn: "AB", FanGraphsColumn: "AB"},
+	{ID: B_BATTING_AVG, Name: "AVG", Side: BATTING, Format: AVERAGE_FORMAT,
+		Rate: &rateFormula{
+			numerator:   []statTerm{{B_HITS, 1, false}},
+			denominator: []statTerm{{B_AT_BATS, 1, false}},
+			scale:       1,
+		},
+		YahooID: 3, ZipsColumn: "BA", FanGraphsColumn: "AVG"},
+	{ID: B_CAUGHT_STEALING, Name: "CS", Side: BATTING, Order: LOWER_IS_BETTER, Format: COUNTING_FORMAT,
+		YahooID: 17, ZipsColumn: "CS", FanGraphsColumn: "CS"},
+	{ID: B_DOUBLES, Name: "2B", Side: BATTING, Format: COUNTING_FORMAT,
+		YahooID: 10, ZipsColumn: "2B", FanGraphsColumn: "2B"},
+	{ID: B_GAMES, Name: "G", Side: BATTING, Format: COUNTING_FORMAT,
+		YahooID: 1, ZipsColumn: "G", FanGraphsColumn: "G"},
+	{ID: B_HITS, Name: "H", Side: BATTING, Format: COUNTING_FORMAT,
+		YahooID: 8, ZipsColumn: "H", FanGraphsColumn: "H"},
+	{ID: B_HOME_RUNS, Name: "HR", Side: BATTING, Format: COUNTING_FORMAT,
+		YahooID: 12, ZipsColumn: "HR", FanGraphsColumn: "HR"},
+	{ID: B_ON_BASE_PCT, Name: "OBP", Side: BATTING, Format: AVERAGE_FORMAT,
+		Rate: &rateFormula{
+			numerator: []statTerm{
+				{B_HITS, 1, false}, {B_WALKS, 1, false}, {B_HIT_BY_PITCH, 1, true}},
+			denominator: []statTerm{
+				{B_AT_BATS, 1, false}, {B_WALKS, 1, false}, {B_HIT_BY_PITCH, 1, true}, {B_SACRIFICE_FLIES, 1, true}},
+			scale: 1,
+		},
+		YahooID: 4, ZipsColumn: "OBP", FanGraphsColumn: "OBP"},
+	{ID: B_PLATE_APPS, Name: "PA", Side: BATTING, Format: COUNTING_FORMAT,
+		YahooID: 65, ZipsColumn: "PA", FanGraphsColumn: "PA"},
+	{ID: B_RUNS, Name: "R", Side: BATTING, Format: COUNTING_FORMAT,
+		YahooID: 7, ZipsColumn: "R", FanGraphsColumn: "R"},
+	{ID: B_RUNS_BATTED_IN, Name: "RBI", Side: BATTING, Format: COUNTING_FORMAT,
+		YahooID: 13, ZipsColumn: "RBI", FanGraphsColumn: "RBI"},
+	{ID: B_SLUGGING, Name: "SLG", Side: BATTING, Format: AVERAGE_FORMAT,
+		Rate: &rateFormula{
+			// Total bases: every hit counts once, plus the extra bases.
+			numerator: []statTerm{
+				{B_HITS, 1, false}, {B_DOUBLES, 1, false}, {B_TRIPLES, 2, false}, {B_HOME_RUNS, 3, false}},
+			denominator: []statTerm{{B_AT_BATS, 1, false}},
+			scale:       1,
+		},
+		YahooID: 5, ZipsColumn: "SLG", FanGraphsColumn: "SLG"},
+	{ID: B_STOLEN_BASES, Name: "SB", Side: BATTING, Format: COUNTING_FORMAT,
+		YahooID: 16, ZipsColumn: "SB", FanGraphsColumn: "SB"},
+	{ID: B_STRIKE_OUTS, Name: "K", Side: BATTING, Order: LOWER_IS_BETTER, Format: COUNTING_FORMAT,
+		YahooID: 21, ZipsColumn: "K", FanGraphsColumn: "SO"},
+	{ID: B_TRIPLES, Name: "3B", Side: BATTING, Format: COUNTING_FORMAT,
+		YahooID: 11, ZipsColumn: "3B", FanGraphsColumn: "3B"},
+	{ID: B_WALKS, Name: "BB", Side: BATTING, Format: COUNTING_FORMAT,
+		YahooID: 18, ZipsColumn: "BB", FanGraphsColumn: "BB"},
+	{ID: B_SINGLES, Name: "1B", Side: BATTING, Format: COUNTING_FORMAT,
+		YahooID: 9, FanGraphsColumn: "1B"},
+	{ID: B_HIT_BY_PITCH, Name: "HBP", Side: BATTING, Format: COUNTING_FORMAT,
+		YahooID: 20, ZipsColumn: "HBP", FanGraphsColumn: "HBP"},
+	{ID: B_SACRIFICE_FLIES, Name: "SF", Side: BATTING, Format: COUNTING_FORMAT,
+		ZipsColumn: "SF", FanGraphsColumn: "SF"},
+
+	{ID: P_EARNED_RUNS, Name: "ER", Side: PITCHING, Order: LOWER_IS_BETTER, Format: COUNTING_FORMAT,
+		YahooID: 37, ZipsColumn: "ER", FanGraphsColumn: "ER"},
+	{ID: P_EARNED_RUN_AVERAGE, Name: "ERA", Side: PITCHING, Order: LOWER_IS_BETTER, Format: ERA_FORMAT,
+		Rate: &rateFormula{
+			numerator:   []statTerm{{P_EARNED_RUNS, 1, false}},
+			denominator: []statTerm{{P_INNINGS, 1, false}},
+			scale:       9,
+		},
+		YahooID: 26, ZipsColumn: "ERA", FanGraphsColumn: "ERA"},
+	{ID: P_GAMES, Name: "G", Side: PITCHING, Format: COUNTING_FORMAT,
+		ZipsColumn: "G", FanGraphsColumn: "G"},
+	{ID: P_HITS, Name: "H", Side: PITCHING, Order: LOWER_IS_BETTER, Format: COUNTING_FORMAT,
+		YahooID: 34, ZipsColumn: "H", FanGraphsColumn: "H"},
+	{ID: P_HOME_RUNS, Name: "HR", Side: PITCHING, Order: LOWER_IS_BETTER, Format: COUNTING_FORMAT,
+		YahooID: 38, ZipsColumn: "HR", FanGraphsColumn: "HR"},
+	{ID: P_INNINGS, Name: "IP", Side: PITCHING, Format: "%.1f",
+		YahooID: 50, ZipsColumn: "IP", FanGraphsColumn: "IP"},
+	{ID: P_LOSSES, Name: "L", Side: PITCHING, Order: LOWER_IS_BETTER, Format: COUNTING_FORMAT,
+		YahooID: 29, ZipsColumn: "L", FanGraphsColumn: "L"},
+	{ID: P_RUNS, Name: "R", Side: PITCHING, Order: LOWER_IS_BETTER, Format: COUNTING_FORMAT,
+		YahooID: 36, ZipsColumn: "R", FanGraphsColumn: "R"},
+	{ID: P_SAVES, Name: "SV", Side: PITCHING, Format: COUNTING_FORMAT,
+		YahooID: 32, FanGraphsColumn: "SV"},
+	{ID: P_STARTS, Name: "GS", Side: PITCHING, Format: COUNTING_FORMAT,
+		YahooID: 62, ZipsColumn: "GS", FanGraphsColumn: "GS"},
+	{ID: P_STRIKE_OUTS, Name: "K", Side: PITCHING, Format: COUNTING_FORMAT,
+		YahooID: 42, ZipsColumn: "SO", FanGraphsColumn: "SO"},
+	{ID: P_WALKS, Name: "BB", Side: PITCHING, Order: LOWER_IS_BETTER, Format: COUNTING_FORMAT,
+		YahooID: 39, ZipsColumn: "BB", FanGraphsColumn: "BB"},
+	{ID: P_WHIP, Name: "WHIP", Side: PITCHING, Order: LOWER_IS_BETTER, Format: ERA_FORMAT,
+		Rate: &rateFormula{
+			numerator:   []statTerm{{P_WALKS, 1, false}, {P_HITS, 1, false}},
+			denominator: []statTerm{{P_INNINGS, 1, false}},
+			scale:       1,
+		},
+		YahooID: 27, FanGraphsColumn: "WHIP"},
+	{ID: P_WINS, Name: "W", Side: PITCHING, Format: COUNTING_FORMAT,
+		YahooID: 28, ZipsColumn: "W", FanGraphsColumn: "W"},
+	{ID: P_BATTERS_FACED, Name: "TBF", Side: PITCHING, Format: COUNTING_FORMAT,
+		YahooID: 35, FanGraphsColumn: "TBF"},
+	{ID: P_SAVE_CHANCES, Name: "SVO", Side: PITCHING, Format: COUNTING_FORMAT,
+		YahooID: 47},
+	{ID: P_QUALITY_STARTS, Name: "QS", Side: PITCHING, Format: COUNTING_FORMAT,
+		YahooID: 83},
+	{ID: P_STRIKEOUTS_PER_9, Name: "K/9", Side: PITCHING, Format: ERA_FORMAT,
+		Rate: &rateFormula{
+			numerator:   []statTerm{{P_STRIKE_OUTS, 1, false}},
+			denominator: []statTerm{{P_INNINGS, 1, false}},
+			scale:       9,
+		},
+		YahooID: 57, FanGraphsColumn: "K/9"},
+}
+
+var statInfoById = indexStatRegistry(statRegistry)
+
+func indexStatRegistry(registry []StatInfo) map[StatID]StatInfo {
+	index := make(map[StatID]StatInfo)
+	for _, info := range registry {
+		if _, dup := index[info.ID]; dup {
+			panic(fmt.Sprintf("Stat %d registered twice", info.ID))
+		}
+		index[info.ID] = info
+	}
+	return index
+}
+
+func lookupStat(s StatID) (StatInfo, bool) {
+	info, ok := statInfoById[s]
+	return info, ok
+}
+
+// A stat's short name, e.g. "HR".
+func StatName(s StatID) string {
+	if info, ok := lookupStat(s); ok {
+		return info.Name
+	}
+	return fmt.Sprintf("Stat %d", s)
+}
+
+// Formats a value of a stat for display, e.g. "HR:32" or "ERA:3.21".
+func FormatStat(s StatID, v Stat) string {
+	format := COUNTING_FORMAT
+	if info, ok := lookupStat(s); ok {
+		format = info.Format
+	}
+	return StatName(s) + ":" + fmt.Sprintf(format, v)
+}
+
+// Formats the given stats from a line, computing rate stats from their
+// components if the line doesn't have them.
+func FormatStats(line StatLine, stats []StatID) string {
+	parts := make([]string, 0, len(stats))
+	for _, s := range stats {
+		parts = append(parts, FormatStat(s, rateOrValue(s, line)))
+	}
+	return strings.Join(parts, " ")
+}
+
+func rateOrValue(s StatID, line StatLine) Stat {
+	if v, ok := line[s]; ok {
+		return v
+	}
+	if formula := rateFormulaFor(s); formula != nil {
+		n, hasNumerator := formula.numeratorOf(line)
+		d, hasDenominator := formula.denominatorOf(line)
+		if hasNumerator && hasDenominator && d > 0 {
+			return formula.scale * n / d
+		}
+	}
+	return 0
+}
+
+func rateFormulaFor(s StatID) *rateFormula {
+	if info, ok := lookupStat(s); ok {
+		return info.Rate
+	}
+	return nil
+}
+
+func isRateStat(s StatID) bool {
+	info, ok := lookupStat(s)
+	return ok && info.IsRate()
+}
+
+// Which direction is better for a stat, when the league doesn't say.
+func defaultSortOrder(s StatID) SortOrder {
+	if info, ok := lookupStat(s); ok {
+		return info.Order
+	}
+	return HIGHER_IS_BETTER
+}
+
+// Maps the ids Yahoo uses for stats to ours.  See:
+// yurl https://fantasysports.yahooapis.com/fantasy/v2/game/328/stat_categories
+func mapYahooIdToStatId() map[int]StatID {
+	m := make(map[int]StatID)
+	for _, info := range statRegistry {
+		if info.YahooID != 0 {
+			m[info.YahooID] = info.ID
+		}
+	}
+	return m
+}
+
+// Built once, rather than for every batch of stats we get from Yahoo.
+var statIdByYahooId = mapYahooIdToStatId()
+
+// Maps column names in ZiPS CSVs for one side (BATTING or PITCHING) to stats.
+func mapZipsColumnToStat(side string) map[ColName]StatID {
+	m := make(map[ColName]StatID)
+	for _, info := range statRegistry {
+		if info.Side == side && info.ZipsColumn != "" {
+			m[info.ZipsColumn] = info.ID
+		}
+	}
+	return m
+}
+
+// Maps column names in FanGraphs CSVs for one side (BATTING or PITCHING) to
+// stats.
+func mapFanGraphsColumnToStat(side string) map[ColName]StatID {
+	m := make(map[ColName]StatID)
+	for _, info := range statRegistry {
+		if info.Side == side && info.FanGraphsColumn != "" {
+			m[info.FanGraphsColumn] = info.ID
+		}
+	}
+	return m
+}
@@ -0,0 +1,38 @@
+package folib
+
+import (
+	"testing"
+)
+
+func TestStatRegistryAliasesAreUnique(t *testing.T) {
+	yahooIds := make(map[int]StatID)
+	columns := map[string]map[ColName]StatID{BATTING: {}, PITCHING: {}}
+	for _, info := range statRegistry {
+		if info.Side != BATTING && info.Side != PITCHING {
+			t.Errorf("Stat %d has unknown side '%s'", info.ID, info.Side)
+			continue
+		}
+		if info.YahooID != 0 {
+			if other, dup := yahooIds[info.YahooID]; dup {
+				t.Errorf("Yahoo id %d used for both %d and %d", info.YahooID, other, info.ID)
+			}
+			yahooIds[info.YahooID] = info.ID
+		}
+		if info.ZipsColumn != "" {
+			if other, dup := columns[info.Side][info.ZipsColumn]; dup {
+				t.Errorf("ZiPS column %s used for both %d and %d", info.ZipsColumn, other, info.ID)
+			}
+			columns[info.Side][info.ZipsColumn] = info.ID
+		}
+	}
+}
+
+func TestFormatStats(t *testing.T) {
+	line := StatLine{P_WINS: 15, P_SAVES: 0, P_STRIKE_OUTS: 201, P_EARNED_RUN_AVERAGE: 3.004,
+		P_WALKS: 50, P_HITS: 170, P_INNINGS: 200}
+
+	expected := "W:15 SV:0 K:201 ERA:3.00 WHIP:1.10"
+	if actual := FormatPitchingStats(line); actual != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, actual)
+	}
+}
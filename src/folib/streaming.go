@@ -0,0 +1,188 @@
+package folib
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Which free agents to consider, and the league's limits on how much
+// pitching counts toward a week's matchup (zero meaning no limit).
+type StreamingOptions struct {
+	// How many free agent starting pitchers to consider.
+	FreeAgents int
+	// The league's weekly cap on games started by my pitchers.  Zero means
+	// the league's own setting, if it has one.
+	MaxStarts int
+	// The league's weekly cap on innings pitched.  Zero means the league's
+	// own setting, if it has one.
+	MaxInnings float64
+}
+
+// A free agent starting pitcher I could stream this week.
+type StreamingOption struct {
+	Pitcher YahooPlayer
+	// The dates of their announced starts, from today through the end of the
+	// matchup.
+	Starts []string
+	// How many of those starts count, given the league's caps.
+	UsableStarts int
+
+	// Their projected line over the usable starts.
+	Line StatLine
+	// How many more categories I expect to win (counting ties as half), and
+	// how each category's chance of winning changes.
+	Delta      float64
+	Categories map[StatID]float64
+}
+
+// Finds free agent starting pitchers with two starts left in this week's
+// matchup, or a start today, and scores how much each would help my
+// matchup.  Needs a schedule with probable pitchers (see SetSchedule).
+func (fo *FO) PlanStreamers(ctx context.Context, date string, options StreamingOptions) ([]StreamingOption, error) {
+	if fo.schedule == nil {
+		return nil, fmt.Errorf("Planning streamers needs the MLB schedule")
+	}
+	today, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := fo.matchupLines(ctx, 0, today)
+	if err != nil {
+		return nil, err
+	}
+	// The scoreboard only tells us about this week, and we can't stream for
+	// any other.
+	if !inMatchup(lines.matchup, date) {
+		return nil, fmt.Errorf("%s isn't in this week's matchup (%s to %s)",
+			date, lines.matchup.WeekStart, lines.matchup.WeekEnd)
+	}
+
+	err = fo.addStartsUsed(ctx, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	freeAgents, err := fo.yahoo.GetFreeAgents(ctx, fo.league.LeagueKey, "SP", options.FreeAgents)
+	if err != nil {
+		return nil, err
+	}
+
+	return fo.planStreamers(lines, freeAgents, date, options), nil
+}
+
+func (fo *FO) planStreamers(lines *matchupLines, freeAgents []YahooPlayer, date string, options StreamingOptions) []StreamingOption {
+	categories := fo.settings.Categories
+	before := projectMatchup(lines.mineActual, lines.mineRest, lines.theirsActual, lines.theirsRest, categories)
+
+	// Caps we weren't given come from the league.
+	if options.MaxStarts == 0 {
+		options.MaxStarts = fo.settings.MaxWeeklyStarts
+	}
+	if options.MaxInnings == 0 {
+		options.MaxInnings = fo.settings.MaxWeeklyInnings
+	}
+	startsLeft, inningsLeft := capRoom(options, lines.mineActual, lines.mineRest)
+
+	plan := []StreamingOption{}
+	for _, pitcher := range freeAgents {
+		if !isStartingPitcher(pitcher) {
+			continue
+		}
+		starts := fo.schedule.ProbableStarts(pitcher.FullName, pitcher.TeamAbbr, lines.start, lines.end)
+		if len(starts) < 2 && (len(starts) == 0 || starts[0] != date) {
+			continue
+		}
+
+		projection := fo.projections.GetStatLine(fo.playerID(pitcher))
+		seasonStarts := float64(projection[P_STARTS])
+		if seasonStarts <= 0 {
+			seasonStarts = SEASON_STARTS
+		}
+		inningsPerStart := float64(projection[P_INNINGS]) / seasonStarts
+
+		usable := min(len(starts), startsLeft)
+		if inningsPerStart > 0 && !math.IsInf(inningsLeft, 1) {
+			usable = min(usable, int(inningsLeft/inningsPerStart))
+		}
+		if usable <= 0 {
+			continue
+		}
+
+		line := scaleStatLine(projection, float64(usable)/seasonStarts)
+		after := projectMatchup(
+			lines.mineActual, merge([]StatLine{lines.mineRest, line}),
+			lines.theirsActual, lines.theirsRest, categories)
+
+		option := StreamingOption{
+			Pitcher:      pitcher,
+			Starts:       starts,
+			UsableStarts: usable,
+			Line:         line,
+			Delta:        matchupPoints(after) - matchupPoints(before),
+			Categories:   make(map[StatID]float64),
+		}
+		for i, c := range after.Categories {
+			option.Categories[c.Stat] = c.WinProbability - before.Categories[i].WinProbability
+		}
+		plan = append(plan, option)
+	}
+
+	sort.SliceStable(plan, func(i, j int) bool {
+		return plan[i].Delta > plan[j].Delta
+	})
+	return plan
+}
+
+// Adds the games my pitchers have started so far this week to what the
+// scoreboard says I've done, since the scoreboard only has the stats the
+// league scores.  This counts the starts of the pitchers on my roster now,
+// whether or not they were in my lineup at the time.
+func (fo *FO) addStartsUsed(ctx context.Context, lines *matchupLines) error {
+	if _, ok := lines.mineActual[P_STARTS]; ok {
+		return nil
+	}
+	keys := []string{}
+	for _, player := range lines.mine {
+		if player.PositionType == PITCHING {
+			keys = append(keys, player.PlayerKey)
+		}
+	}
+	stats, err := fo.yahoo.GetStats(ctx, keys, StatsScope{Week: lines.matchup.Week})
+	if err != nil {
+		return err
+	}
+
+	starts := Stat(0)
+	for _, line := range stats {
+		starts += line[P_STARTS]
+	}
+	lines.mineActual[P_STARTS] = starts
+	return nil
+}
+
+// Whether a date (e.g. "2014-05-01") falls in a matchup's week.
+func inMatchup(matchup *YahooMatchup, date string) bool {
+	return date >= matchup.WeekStart && date <= matchup.WeekEnd
+}
+
+// How many more starts and innings fit under the league's caps, after what
+// my pitchers have already thrown this week and are projected to throw.
+func capRoom(options StreamingOptions, actual, rest StatLine) (starts int, innings float64) {
+	starts, innings = math.MaxInt32, math.Inf(1)
+	if options.MaxStarts > 0 {
+		starts = options.MaxStarts - int(math.Round(float64(actual[P_STARTS]+rest[P_STARTS])))
+	}
+	if options.MaxInnings > 0 {
+		innings = options.MaxInnings - float64(actual[P_INNINGS]+rest[P_INNINGS])
+	}
+	return starts, innings
+}
+
+// The expected number of categories won, counting ties as half a win.
+func matchupPoints(projection *MatchupProjection) float64 {
+	return projection.ExpectedWins + projection.ExpectedTies/2
+}
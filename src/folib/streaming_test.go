@@ -0,0 +1,164 @@
+package folib
+
+import (
+	"context"
+	"encoding/xml"
+	"testing"
+)
+
+const streamingScoreboardXml = `<?xml version="1.0" encoding="UTF-8"?>
+<fantasy_content>
+  <league>
+    <scoreboard>
+      <week>5</week>
+      <matchups>
+        <matchup>
+          <week>5</week>
+          <week_start>2014-04-28</week_start>
+          <week_end>2014-05-04</week_end>
+          <teams>
+            <team>
+              <team_key>328.l.1.t.1</team_key>
+              <team_stats><stats>
+                <stat><stat_id>50</stat_id><value>45.0</value></stat>
+                <stat><stat_id>42</stat_id><value>40</value></stat>
+                <stat><stat_id>26</stat_id><value>4.00</value></stat>
+              </stats></team_stats>
+            </team>
+            <team><team_key>328.l.1.t.2</team_key></team>
+          </teams>
+        </matchup>
+      </matchups>
+    </scoreboard>
+  </league>
+</fantasy_content>`
+
+const myPitchersStatsXml = `<?xml version="1.0"?>
+<fantasy_content>
+  <players>
+    <player><player_key>328.p.10</player_key><player_stats><stats>
+      <stat><stat_id>62</stat_id><value>4</value></stat>
+      <stat><stat_id>50</stat_id><value>27.1</value></stat>
+    </stats></player_stats></player>
+    <player><player_key>328.p.11</player_key><player_stats><stats>
+      <stat><stat_id>62</stat_id><value>3</value></stat>
+      <stat><stat_id>50</stat_id><value>17.2</value></stat>
+    </stats></player_stats></player>
+  </players>
+</fantasy_content>`
+
+func TestPlanStreamers(t *testing.T) {
+	fo := testFO(fakeStatsClient{
+		"Ace":              {P_STRIKE_OUTS: 224, P_INNINGS: 192, P_EARNED_RUNS: 64, P_EARNED_RUN_AVERAGE: 3, P_STARTS: 32},
+		"Today":            {P_STRIKE_OUTS: 160, P_INNINGS: 160, P_EARNED_RUNS: 64, P_EARNED_RUN_AVERAGE: 3.6, P_STARTS: 32},
+		"Tomorrow":         {P_STRIKE_OUTS: 200, P_INNINGS: 180, P_EARNED_RUNS: 60, P_EARNED_RUN_AVERAGE: 3, P_STARTS: 32},
+		"Batting Practice": {P_STRIKE_OUTS: 96, P_INNINGS: 160, P_EARNED_RUNS: 120, P_EARNED_RUN_AVERAGE: 6.75, P_STARTS: 32},
+	}, &LeagueSettings{
+		Categories: ScoringCategories{
+			P_STRIKE_OUTS:        HIGHER_IS_BETTER,
+			P_EARNED_RUN_AVERAGE: LOWER_IS_BETTER,
+		},
+	})
+	fo.schedule = NewSchedule([]ScheduledGame{
+		{Date: "2014-05-01", Away: "NYY", Home: "BOS", HomeProbable: "Today"},
+		{Date: "2014-05-01", Away: "DET", Home: "CWS", AwayProbable: "Batting Practice"},
+		{Date: "2014-05-02", Away: "NYY", Home: "BOS", AwayProbable: "Ace", HomeProbable: "Tomorrow"},
+		{Date: "2014-05-03", Away: "DET", Home: "CWS", AwayProbable: "Batting Practice"},
+		{Date: "2014-05-04", Away: "NYY", Home: "TB", AwayProbable: "Ace"},
+	})
+
+	// The scoreboard doesn't have starts, so they come from my pitchers' stats.
+	var data FantasyContent
+	if err := xml.Unmarshal([]byte(streamingScoreboardXml), &data); err != nil {
+		t.Fatal(err)
+	}
+	matchup, mine, _, err := findMatchup(&data.League.Scoreboard, "328.l.1.t.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mineActual, err := parseTeamStats(mine.Stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	yahoo, client := newFakeYahoo(t)
+	yahoo.respond("/players;player_keys=328.p.10,328.p.11/stats;type=week;week=5", myPitchersStatsXml)
+	fo.yahoo = client
+
+	lines := &matchupLines{
+		matchup: matchup,
+		mine: []YahooPlayer{
+			{PlayerKey: "328.p.9", FullName: "Slugger", PositionType: "B"},
+			{PlayerKey: "328.p.10", FullName: "Workhorse", PositionType: "P"},
+			{PlayerKey: "328.p.11", FullName: "Fifth Starter", PositionType: "P"},
+		},
+		start:        day("2014-05-01"),
+		end:          day("2014-05-04"),
+		mineActual:   mineActual,
+		mineRest:     StatLine{P_STRIKE_OUTS: 10, P_INNINGS: 12, P_EARNED_RUNS: 4, P_EARNED_RUN_AVERAGE: 3, P_STARTS: 2},
+		theirsActual: StatLine{P_STRIKE_OUTS: 50, P_INNINGS: 50, P_EARNED_RUNS: 20, P_EARNED_RUN_AVERAGE: 3.6, P_STARTS: 8},
+		theirsRest:   StatLine{P_STRIKE_OUTS: 12, P_INNINGS: 12, P_EARNED_RUNS: 4, P_EARNED_RUN_AVERAGE: 3, P_STARTS: 2},
+	}
+	freeAgents := []YahooPlayer{
+		{FullName: "Ace", PositionType: "P", Position: []string{"SP"}, TeamAbbr: "NYY"},
+		{FullName: "Today", PositionType: "P", Position: []string{"SP"}, TeamAbbr: "Bos"},
+		{FullName: "Tomorrow", PositionType: "P", Position: []string{"SP"}, TeamAbbr: "BOS"},
+		{FullName: "Batting Practice", PositionType: "P", Position: []string{"SP"}, TeamAbbr: "DET"},
+		{FullName: "Mop Up", PositionType: "P", Position: []string{"RP"}, TeamAbbr: "NYY"},
+	}
+
+	if err := fo.addStartsUsed(context.Background(), lines); err != nil {
+		t.Fatal(err)
+	}
+	if lines.mineActual[P_STARTS] != 7 || lines.mineActual[P_INNINGS] != 45 {
+		t.Errorf("Expected 7 starts and 45 innings so far, got: %v", lines.mineActual)
+	}
+
+	plan := fo.planStreamers(lines, freeAgents, "2014-05-01", StreamingOptions{})
+	if len(plan) != 3 {
+		t.Fatalf("Expected two-start pitchers and today's starter, got: %v", plan)
+	}
+	if plan[0].Pitcher.FullName != "Ace" || plan[0].UsableStarts != 2 {
+		t.Errorf("Expected Ace's two starts to help most, got %s (%d)", plan[0].Pitcher.FullName, plan[0].UsableStarts)
+	}
+	if plan[0].Categories[P_STRIKE_OUTS] <= 0 {
+		t.Errorf("Expected Ace to help in strikeouts, got: %v", plan[0].Categories)
+	}
+	last := plan[len(plan)-1]
+	if last.Pitcher.FullName != "Batting Practice" || last.Categories[P_EARNED_RUN_AVERAGE] >= 0 {
+		t.Errorf("Expected Batting Practice to hurt my ERA most, got %s: %v", last.Pitcher.FullName, last.Categories)
+	}
+
+	// With one start left under the cap, two-start pitchers only get one.
+	plan = fo.planStreamers(lines, freeAgents, "2014-05-01", StreamingOptions{MaxStarts: 10})
+	for _, option := range plan {
+		if option.UsableStarts != 1 {
+			t.Errorf("Expected %s to get one start under the cap, got %d", option.Pitcher.FullName, option.UsableStarts)
+		}
+	}
+
+	// No room for even one start's innings.
+	plan = fo.planStreamers(lines, freeAgents, "2014-05-01", StreamingOptions{MaxInnings: 60})
+	if len(plan) != 0 {
+		t.Errorf("Expected nobody to fit under the innings cap, got: %v", plan)
+	}
+	// The same, with the league's cap.
+	fo.settings.MaxWeeklyInnings = 60
+	plan = fo.planStreamers(lines, freeAgents, "2014-05-01", StreamingOptions{})
+	if len(plan) != 0 {
+		t.Errorf("Expected nobody to fit under the league's innings cap, got: %v", plan)
+	}
+}
+
+func TestInMatchup(t *testing.T) {
+	matchup := &YahooMatchup{WeekStart: "2014-04-28", WeekEnd: "2014-05-04"}
+	for date, expected := range map[string]bool{
+		"2014-04-27": false,
+		"2014-04-28": true,
+		"2014-05-04": true,
+		"2014-05-05": false,
+	} {
+		if inMatchup(matchup, date) != expected {
+			t.Errorf("%s: expected in matchup to be %t", date, expected)
+		}
+	}
+}
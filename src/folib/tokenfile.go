@@ -0,0 +1,142 @@
+package folib
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The current token file format.  Files without a version are the bare
+// OAuth2Token JSON we used to write.
+const TOKEN_FILE_VERSION = 1
+
+// Returned for token files in the OAuth1 "len|token|len|secret" format,
+// whose tokens Yahoo no longer accepts.
+var ErrLegacyToken = errors.New("OAuth1 token from an old token file")
+
+type tokenFileContents struct {
+	Version int          `json:"version"`
+	Token   *OAuth2Token `json:"token"`
+}
+
+// Reads a saved token, returning nil if there isn't one.  The version is
+// that of the file's format, so callers can tell if it needs rewriting.
+func ReadTokenFile(filename string) (token *OAuth2Token, version int, err error) {
+	bits, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, TOKEN_FILE_VERSION, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return parseTokenFile(bits)
+}
+
+func parseTokenFile(bits []byte) (*OAuth2Token, int, error) {
+	bits = bytes.TrimSpace(bits)
+	if len(bits) == 0 {
+		return nil, TOKEN_FILE_VERSION, nil
+	}
+	if bits[0] != '{' {
+		if _, _, err := parseLegacyToken(string(bits)); err != nil {
+			return nil, 0, err
+		}
+		return nil, 0, ErrLegacyToken
+	}
+
+	var contents struct {
+		tokenFileContents
+		// Unversioned files have the token's fields at the top level.
+		OAuth2Token
+	}
+	if err := json.Unmarshal(bits, &contents); err != nil {
+		return nil, 0, err
+	}
+
+	switch {
+	case contents.Version > TOKEN_FILE_VERSION:
+		return nil, contents.Version, fmt.Errorf(
+			"Token file version %d is newer than we understand (%d)", contents.Version, TOKEN_FILE_VERSION)
+	case contents.Version == 0 && contents.AccessToken != "":
+		token := contents.OAuth2Token
+		return &token, 0, nil
+	case contents.Version < 0 || contents.Token == nil || contents.Token.AccessToken == "":
+		return nil, contents.Version, fmt.Errorf("No token in token file")
+	}
+	return contents.Token, contents.Version, nil
+}
+
+// Saves a token, readable only by the current user.  The file is replaced
+// atomically, so a crash mid-write can't leave a truncated token behind.
+func WriteTokenFile(filename string, token *OAuth2Token) error {
+	bits, err := json.MarshalIndent(tokenFileContents{Version: TOKEN_FILE_VERSION, Token: token}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	// TempFile already creates files as 0600, but make sure.
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(append(bits, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filename)
+}
+
+// Parses the OAuth1 "len|token|len|secret" format, e.g. "3|abc|2|xy".
+func parseLegacyToken(s string) (token, secret string, err error) {
+	token, rest, err := takeLengthPrefixed(s)
+	if err != nil {
+		return "", "", err
+	}
+	if !strings.HasPrefix(rest, "|") {
+		return "", "", fmt.Errorf("Malformed token: expected '|' after the token")
+	}
+	secret, rest, err = takeLengthPrefixed(rest[1:])
+	if err != nil {
+		return "", "", err
+	}
+	if rest != "" {
+		return "", "", fmt.Errorf("Malformed token: %d extra bytes", len(rest))
+	}
+	return token, secret, nil
+}
+
+// Splits "len|value..." into the value and whatever follows it.
+func takeLengthPrefixed(s string) (value, rest string, err error) {
+	bar := strings.Index(s, "|")
+	if bar == -1 {
+		return "", "", fmt.Errorf("Malformed token: no length")
+	}
+	n, err := strconv.Atoi(s[:bar])
+	if err != nil || n < 0 {
+		return "", "", fmt.Errorf("Malformed token: bad length %q", s[:bar])
+	}
+	s = s[bar+1:]
+	if n > len(s) {
+		return "", "", fmt.Errorf("Malformed token: wanted %d bytes, only %d left", n, len(s))
+	}
+	return s[:n], s[n:], nil
+}
@@ -0,0 +1,185 @@
+package folib
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteTokenFile(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "token")
+	// An old, world-readable token file.
+	if err := ioutil.WriteFile(filename, []byte("3|abc|2|xy"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	token := &OAuth2Token{
+		AccessToken:  "access",
+		RefreshToken: "refresh",
+		TokenType:    "bearer",
+		Expiry:       time.Date(2014, 5, 1, 12, 0, 0, 0, time.UTC),
+		Scope:        "fspt-w",
+	}
+	if err := WriteTokenFile(filename, token); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected 0600, got %v", info.Mode().Perm())
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Errorf("Expected the temporary file to be cleaned up, got %d files", len(files))
+	}
+
+	read, version, err := ReadTokenFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != TOKEN_FILE_VERSION || !reflect.DeepEqual(read, token) {
+		t.Errorf("Expected %v (version %d), got %v (version %d)", token, TOKEN_FILE_VERSION, read, version)
+	}
+}
+
+func TestReadTokenFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "token")
+
+	token, _, err := ReadTokenFile(filename)
+	if token != nil || err != nil {
+		t.Errorf("Expected no token for a missing file, got %v, %v", token, err)
+	}
+
+	examples := []struct {
+		contents string
+		token    string
+		version  int
+		err      bool
+	}{
+		{`{"version": 1, "token": {"access_token": "a"}}`, "a", 1, false},
+		// What we wrote before token files were versioned.
+		{`{"access_token": "a", "refresh_token": "r"}`, "a", 0, false},
+		{`{"version": 2, "token": {"access_token": "a"}}`, "", 2, true},
+		{`{"version": 1}`, "", 1, true},
+		{`{"access_token": `, "", 0, true},
+		{"3|abc|2|xy", "", 0, true},
+		{"", "", TOKEN_FILE_VERSION, false},
+	}
+	for _, example := range examples {
+		if err := ioutil.WriteFile(filename, []byte(example.contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+		token, version, err := ReadTokenFile(filename)
+		if (err != nil) != example.err {
+			t.Errorf("%q: expected error %v, got %v", example.contents, example.err, err)
+		}
+		if (token != nil && token.AccessToken != example.token) || (token == nil && example.token != "") {
+			t.Errorf("%q: expected token %q, got %v", example.contents, example.token, token)
+		}
+		if version != example.version {
+			t.Errorf("%q: expected version %d, got %d", example.contents, example.version, version)
+		}
+	}
+
+	if _, _, err := parseTokenFile([]byte("3|abc|2|xy")); err != ErrLegacyToken {
+		t.Errorf("Expected an old OAuth1 token to be recognized, got %v", err)
+	}
+}
+
+func TestTokenProviderMigratesTokenFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "token")
+	unversioned := `{"access_token": "a", "refresh_token": "r", "expiry": "0001-01-01T00:00:00Z"}`
+	if err := ioutil.WriteFile(filename, []byte(unversioned), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewYahooTokenProvider("id", "secret", OOB_REDIRECT, filename)
+	token, err := p.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "a" {
+		t.Errorf("Expected the saved token, got %v", token)
+	}
+
+	_, version, err := ReadTokenFile(filename)
+	if err != nil || version != TOKEN_FILE_VERSION {
+		t.Errorf("Expected the file to be rewritten as version %d, got %d (%v)", TOKEN_FILE_VERSION, version, err)
+	}
+	if info, _ := os.Stat(filename); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the rewritten file to be private, got %v", info.Mode().Perm())
+	}
+}
+
+func TestParseLegacyToken(t *testing.T) {
+	token, secret, err := parseLegacyToken("3|a|c|4|xy|z")
+	if err != nil || token != "a|c" || secret != "xy|z" {
+		t.Errorf("Expected a|c and xy|z, got %q, %q, %v", token, secret, err)
+	}
+
+	for _, truncated := range []string{"", "3", "3|ab", "3|abc", "3|abc|", "3|abc|2", "3|abc|5|xy", "3|abc|2|xyz", "-1|abc|2|xy", "x|abc"} {
+		if _, _, err := parseLegacyToken(truncated); err == nil {
+			t.Errorf("Expected an error for %q", truncated)
+		}
+	}
+}
+
+func FuzzParseLegacyToken(f *testing.F) {
+	for _, seed := range []string{"3|abc|2|xy", "0||0|", "3|abc|", "10|abc|2|xy", "-1|", "|||"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		token, secret, err := parseLegacyToken(s)
+		if err != nil {
+			return
+		}
+		// Whatever we parse, we can format and parse back the same.
+		formatted := fmt.Sprintf("%d|%s|%d|%s", len(token), token, len(secret), secret)
+		token2, secret2, err := parseLegacyToken(formatted)
+		if err != nil || token2 != token || secret2 != secret {
+			t.Errorf("%q parsed as %q, %q, but %q parsed as %q, %q (%v)", s, token, secret, formatted, token2, secret2, err)
+		}
+	})
+}
+
+func FuzzParseTokenFile(f *testing.F) {
+	for _, seed := range []string{
+		`{"version": 1, "token": {"access_token": "a", "refresh_token": "r", "expiry": "2014-05-01T12:00:00Z"}}`,
+		`{"access_token": "a"}`,
+		`{"version": 9}`,
+		`{"version": -1, "token": {"access_token": "a"}}`,
+		"3|abc|2|xy",
+		"",
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, bits []byte) {
+		token, _, err := parseTokenFile(bits)
+		if err != nil || token == nil {
+			return
+		}
+
+		// Anything we can read, we can write and read back.
+		filename := filepath.Join(t.TempDir(), "token")
+		if err := WriteTokenFile(filename, token); err != nil {
+			t.Fatal(err)
+		}
+		again, version, err := ReadTokenFile(filename)
+		if err != nil || version != TOKEN_FILE_VERSION {
+			t.Fatalf("Couldn't reread %v: %v", token, err)
+		}
+		if again.AccessToken != token.AccessToken || again.RefreshToken != token.RefreshToken ||
+			!again.Expiry.Equal(token.Expiry) || again.Scope != token.Scope {
+			t.Errorf("Expected %v, got %v", token, again)
+		}
+	})
+}
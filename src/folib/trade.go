@@ -0,0 +1,250 @@
+package folib
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// One team's half of a trade.  Players can be named by their Yahoo player
+// key, our PlayerID for them, or their full name.
+type TradeSide struct {
+	Team  TeamID
+	Gives []PlayerID
+	// Players to drop after the trade, e.g. to make room for the extra
+	// players in a 2-for-1.  These can include players received in the trade.
+	Drops []PlayerID
+}
+
+type TradeProposal struct {
+	A TradeSide
+	B TradeSide
+}
+
+func (p TradeProposal) String() string {
+	s := fmt.Sprintf("TEAM %d gives %s; TEAM %d gives %s",
+		p.A.Team, joinPlayerIDs(p.A.Gives), p.B.Team, joinPlayerIDs(p.B.Gives))
+	if len(p.A.Drops) > 0 {
+		s += fmt.Sprintf("; TEAM %d drops %s", p.A.Team, joinPlayerIDs(p.A.Drops))
+	}
+	if len(p.B.Drops) > 0 {
+		s += fmt.Sprintf("; TEAM %d drops %s", p.B.Team, joinPlayerIDs(p.B.Drops))
+	}
+	return s
+}
+
+func joinPlayerIDs(ids []PlayerID) string {
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = string(id)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Returned when a player named in a trade isn't on the team, or when more
+// than one player on the team goes by that name.
+type PlayerLookupError struct {
+	Player PlayerID
+	Team   TeamID
+	// Everyone the name matched.  Empty if it didn't match anyone.
+	Matches []YahooPlayer
+}
+
+func (e *PlayerLookupError) Error() string {
+	if len(e.Matches) == 0 {
+		return fmt.Sprintf("No player %s on team %d", e.Player, e.Team)
+	}
+	keys := make([]string, len(e.Matches))
+	for i, m := range e.Matches {
+		keys[i] = fmt.Sprintf("%s (%s)", m.FullName, m.PlayerKey)
+	}
+	return fmt.Sprintf("Player %s is ambiguous on team %d: %s",
+		e.Player, e.Team, strings.Join(keys, ", "))
+}
+
+func (e *PlayerLookupError) Ambiguous() bool {
+	return len(e.Matches) > 1
+}
+
+// How a trade changes the projected standings.
+type TradeEvaluation struct {
+	Proposal TradeProposal
+
+	// Projected full-season totals for every team.
+	Before map[TeamID]StatLine
+	After  map[TeamID]StatLine
+
+	BeforeScores map[TeamID]float32
+	AfterScores  map[TeamID]float32
+	// How many points each team gains (or loses) from the trade.
+	Deltas map[TeamID]float32
+}
+
+// Projects the league with and without a trade.  The rosters passed in are
+// left untouched.
+func (fo *FO) EvaluateTrade(ctx context.Context, rosters map[TeamID][]YahooPlayer, proposal TradeProposal) (*TradeEvaluation, error) {
+	err := fo.loadSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return fo.evaluateTrade(rosters, fo.projectLeague(&rosters), proposal)
+}
+
+// Same as EvaluateTrade, given the league's projections without the trade.
+// Only the two teams in the trade are re-projected.
+func (fo *FO) evaluateTrade(rosters map[TeamID][]YahooPlayer, before map[TeamID]StatLine, proposal TradeProposal) (*TradeEvaluation, error) {
+	after, err := applyTrade(rosters, proposal, fo.playerID, fo.settings.Topology)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluation := &TradeEvaluation{
+		Proposal: proposal,
+		Before:   before,
+		After:    make(map[TeamID]StatLine),
+		Deltas:   make(map[TeamID]float32),
+	}
+	for team, line := range before {
+		evaluation.After[team] = line
+	}
+	for _, team := range []TeamID{proposal.A.Team, proposal.B.Team} {
+		evaluation.After[team] = fo.projectRoster(after[team], 0)
+	}
+
+	evaluation.BeforeScores = scoreLeague(evaluation.Before, fo.settings.Scorer())
+	evaluation.AfterScores = scoreLeague(evaluation.After, fo.settings.Scorer())
+	for team := range evaluation.BeforeScores {
+		evaluation.Deltas[team] = evaluation.AfterScores[team] - evaluation.BeforeScores[team]
+	}
+	return evaluation, nil
+}
+
+// Which team a player is on.
+func (fo *FO) teamOf(rosters map[TeamID][]YahooPlayer, player PlayerID) (TeamID, error) {
+	matches := []YahooPlayer{}
+	team := TeamID(-1)
+	for t, roster := range rosters {
+		for _, i := range findPlayer(roster, player, fo.playerID) {
+			matches = append(matches, roster[i])
+			team = t
+		}
+	}
+	if len(matches) != 1 {
+		return -1, &PlayerLookupError{Player: player, Team: -1, Matches: matches}
+	}
+	return team, nil
+}
+
+// Returns a copy of the rosters with the trade made.  Fails if any of the
+// players can't be found, or if either team ends up with more active players
+// than its roster allows.
+func applyTrade(rosters map[TeamID][]YahooPlayer, proposal TradeProposal, idOf func(YahooPlayer) PlayerID, topology RosterTopology) (map[TeamID][]YahooPlayer, error) {
+	a, b := proposal.A, proposal.B
+	if a.Team == b.Team {
+		return nil, fmt.Errorf("Can't trade team %d with itself", a.Team)
+	}
+	for _, team := range []TeamID{a.Team, b.Team} {
+		if _, ok := rosters[team]; !ok {
+			return nil, fmt.Errorf("No team %d", team)
+		}
+	}
+
+	result := copyRosters(rosters)
+
+	aGives, aKeeps, err := takePlayers(result[a.Team], a.Team, a.Gives, idOf)
+	if err != nil {
+		return nil, err
+	}
+	bGives, bKeeps, err := takePlayers(result[b.Team], b.Team, b.Gives, idOf)
+	if err != nil {
+		return nil, err
+	}
+
+	_, aKeeps, err = takePlayers(append(aKeeps, bGives...), a.Team, a.Drops, idOf)
+	if err != nil {
+		return nil, err
+	}
+	_, bKeeps, err = takePlayers(append(bKeeps, aGives...), b.Team, b.Drops, idOf)
+	if err != nil {
+		return nil, err
+	}
+
+	result[a.Team] = aKeeps
+	result[b.Team] = bKeeps
+
+	if limit := topology.ActiveSize(); limit > 0 {
+		for _, team := range []TeamID{a.Team, b.Team} {
+			before, after := activeCount(rosters[team]), activeCount(result[team])
+			if after > limit && after > before {
+				return nil, fmt.Errorf(
+					"Team %d would have %d active players, but only has room for %d", team, after, limit)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Splits a roster into the named players, and everyone else.
+func takePlayers(roster []YahooPlayer, team TeamID, players []PlayerID, idOf func(YahooPlayer) PlayerID) ([]YahooPlayer, []YahooPlayer, error) {
+	taken := make(map[int]bool)
+	for _, player := range players {
+		matches := findPlayer(roster, player, idOf)
+		if len(matches) != 1 {
+			err := &PlayerLookupError{Player: player, Team: team}
+			for _, i := range matches {
+				err.Matches = append(err.Matches, roster[i])
+			}
+			return nil, nil, err
+		}
+		if taken[matches[0]] {
+			return nil, nil, fmt.Errorf("Player %s is listed twice for team %d", player, team)
+		}
+		taken[matches[0]] = true
+	}
+
+	took, kept := []YahooPlayer{}, []YahooPlayer{}
+	for i, player := range roster {
+		if taken[i] {
+			took = append(took, player)
+		} else {
+			kept = append(kept, player)
+		}
+	}
+	return took, kept, nil
+}
+
+// The indices of every player on a roster who goes by the given name.
+func findPlayer(roster []YahooPlayer, player PlayerID, idOf func(YahooPlayer) PlayerID) []int {
+	matches := []int{}
+	for i, p := range roster {
+		if string(player) == p.PlayerKey || player == PlayerID(p.FullName) || player == idOf(p) {
+			matches = append(matches, i)
+		}
+	}
+	return matches
+}
+
+func activeCount(roster []YahooPlayer) int {
+	n := 0
+	for _, player := range roster {
+		if !isInactive(player) {
+			n++
+		}
+	}
+	return n
+}
+
+func copyRosters(rosters map[TeamID][]YahooPlayer) map[TeamID][]YahooPlayer {
+	copied := make(map[TeamID][]YahooPlayer)
+	for team, roster := range rosters {
+		copied[team] = make([]YahooPlayer, len(roster))
+		for i, player := range roster {
+			player.Position = append([]string{}, player.Position...)
+			player.StartingStatus = append([]YahooStartingStatus{}, player.StartingStatus...)
+			copied[team][i] = player
+		}
+	}
+	return copied
+}
@@ -0,0 +1,132 @@
+package folib
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func hitter(key, name string) YahooPlayer {
+	return YahooPlayer{PlayerKey: key, FullName: name, PositionType: "B", Position: []string{"OF"}}
+}
+
+func testRosters() map[TeamID][]YahooPlayer {
+	return map[TeamID][]YahooPlayer{
+		1: {hitter("p.1", "Slugger"), hitter("p.2", "Speedster"), hitter("p.3", "Scrub")},
+		2: {hitter("p.4", "Masher"), hitter("p.5", "Chris Young"), hitter("p.6", "Chris Young")},
+	}
+}
+
+func namesOf(roster []YahooPlayer) []string {
+	names := []string{}
+	for _, p := range roster {
+		names = append(names, p.FullName)
+	}
+	return names
+}
+
+func nameAsID(p YahooPlayer) PlayerID {
+	return PlayerID(p.FullName)
+}
+
+func TestApplyTrade(t *testing.T) {
+	rosters := testRosters()
+	topology := RosterTopology{Starters: map[Position]int{"OF": 2}, Bench: 1}
+
+	// A 2-for-1, where team 2 drops someone to make room.
+	after, err := applyTrade(rosters, TradeProposal{
+		A: TradeSide{Team: 1, Gives: []PlayerID{"Slugger", "p.2"}},
+		B: TradeSide{Team: 2, Gives: []PlayerID{"Masher"}, Drops: []PlayerID{"p.6"}},
+	}, nameAsID, topology)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(namesOf(after[1]), []string{"Scrub", "Masher"}) {
+		t.Errorf("Wrong roster for team 1: %v", namesOf(after[1]))
+	}
+	if !reflect.DeepEqual(namesOf(after[2]), []string{"Chris Young", "Slugger", "Speedster"}) {
+		t.Errorf("Wrong roster for team 2: %v", namesOf(after[2]))
+	}
+	if after[2][0].PlayerKey != "p.5" {
+		t.Errorf("Dropped the wrong Chris Young: %v", after[2][0])
+	}
+
+	if !reflect.DeepEqual(rosters, testRosters()) {
+		t.Errorf("The original rosters shouldn't change, got: %v", rosters)
+	}
+}
+
+func TestApplyTradeErrors(t *testing.T) {
+	rosters := testRosters()
+	topology := RosterTopology{Starters: map[Position]int{"OF": 2}, Bench: 1}
+
+	_, err := applyTrade(rosters, TradeProposal{
+		A: TradeSide{Team: 1, Gives: []PlayerID{"Nobody"}},
+		B: TradeSide{Team: 2, Gives: []PlayerID{"Masher"}},
+	}, nameAsID, topology)
+	if lookup, ok := err.(*PlayerLookupError); !ok || lookup.Ambiguous() || lookup.Team != 1 {
+		t.Errorf("Expected an unknown player error, got: %v", err)
+	}
+
+	_, err = applyTrade(rosters, TradeProposal{
+		A: TradeSide{Team: 1, Gives: []PlayerID{"Slugger"}},
+		B: TradeSide{Team: 2, Gives: []PlayerID{"Chris Young"}},
+	}, nameAsID, topology)
+	if lookup, ok := err.(*PlayerLookupError); !ok || !lookup.Ambiguous() || len(lookup.Matches) != 2 {
+		t.Errorf("Expected an ambiguous player error, got: %v", err)
+	}
+
+	// Team 2 would have four players, with room for three.
+	_, err = applyTrade(rosters, TradeProposal{
+		A: TradeSide{Team: 1, Gives: []PlayerID{"Slugger", "Speedster"}},
+		B: TradeSide{Team: 2, Gives: []PlayerID{"Masher"}},
+	}, nameAsID, topology)
+	if err == nil {
+		t.Errorf("Expected an error for an oversized roster")
+	}
+
+	_, err = applyTrade(rosters, TradeProposal{
+		A: TradeSide{Team: 1, Gives: []PlayerID{"Slugger"}},
+		B: TradeSide{Team: 3, Gives: []PlayerID{"Masher"}},
+	}, nameAsID, topology)
+	if err == nil {
+		t.Errorf("Expected an error for an unknown team")
+	}
+}
+
+func TestEvaluateTrade(t *testing.T) {
+	categories := ScoringCategories{
+		B_HOME_RUNS:    HIGHER_IS_BETTER,
+		B_STOLEN_BASES: HIGHER_IS_BETTER,
+	}
+	fo := testFO(fakeStatsClient{
+		"Slugger":   {B_HOME_RUNS: 40, B_STOLEN_BASES: 0},
+		"Speedster": {B_HOME_RUNS: 5, B_STOLEN_BASES: 50},
+		"Scrub":     {B_HOME_RUNS: 1, B_STOLEN_BASES: 0},
+		"Masher":    {B_HOME_RUNS: 35, B_STOLEN_BASES: 0},
+	}, &LeagueSettings{
+		Categories: categories,
+		Topology:   RosterTopology{Starters: map[Position]int{"OF": 2}, Bench: 1},
+	})
+
+	rosters := testRosters()
+	evaluation, err := fo.EvaluateTrade(context.Background(), rosters, TradeProposal{
+		A: TradeSide{Team: 1, Gives: []PlayerID{"Speedster"}},
+		B: TradeSide{Team: 2, Gives: []PlayerID{"Masher"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if evaluation.Before[1][B_HOME_RUNS] != 45 || evaluation.After[1][B_HOME_RUNS] != 75 {
+		t.Errorf("Expected team 1 to go from 45 to 75 HR, got: %v -> %v", evaluation.Before[1], evaluation.After[1])
+	}
+	// Team 1 wins both categories before the trade, and splits them after.
+	if evaluation.Deltas[1] != -1 || evaluation.Deltas[2] != 1 {
+		t.Errorf("Expected team 1 to lose a point to team 2, got: %v", evaluation.Deltas)
+	}
+	if !reflect.DeepEqual(rosters, testRosters()) {
+		t.Errorf("Evaluating a trade shouldn't change the rosters")
+	}
+}
@@ -0,0 +1,225 @@
+package folib
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+type TradeSearchOptions struct {
+	// How many of each team's most valuable players to consider trading.
+	// Zero means everyone.
+	PlayersPerTeam int
+	// The most trades to return.  Zero means all of them.
+	MaxResults int
+	// How many trades to evaluate at once.  Zero means one per CPU.
+	Workers int
+}
+
+// Searches for trades between my team and every other team which help me
+// without hurting them.  Each of my players (or pairs of players) is tried
+// against each of theirs, i.e. 1-for-1, 2-for-1 and 1-for-2 trades.  When a
+// team would end up with too many players, it drops its least valuable one.
+//
+// Trades are ranked by how much they help me, and then by how much they help
+// my trading partner.
+func (fo *FO) FindTrades(ctx context.Context, rosters map[TeamID][]YahooPlayer, options TradeSearchOptions) ([]*TradeEvaluation, error) {
+	err := fo.loadSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The workers only read the ids.
+	fo = fo.withPlayerIDs(rosters)
+
+	me := fo.league.MyTeamID
+	before := fo.projectLeague(&rosters)
+	values := fo.playerValues(rosters)
+
+	proposals := make(chan TradeProposal)
+	go func() {
+		defer close(proposals)
+		mine := tradeCandidates(rosters[me], values, options.PlayersPerTeam)
+		for team, roster := range rosters {
+			if team == me {
+				continue
+			}
+			theirs := tradeCandidates(roster, values, options.PlayersPerTeam)
+			for _, gives := range playerCombinations(mine) {
+				for _, gets := range playerCombinations(theirs) {
+					if len(gives) > 1 && len(gets) > 1 {
+						continue
+					}
+					proposals <- fo.withDrops(rosters, values, TradeProposal{
+						A: TradeSide{Team: me, Gives: tradeRefs(rosters[me], gives)},
+						B: TradeSide{Team: team, Gives: tradeRefs(roster, gets)},
+					})
+				}
+			}
+		}
+	}()
+
+	workers := options.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := []*TradeEvaluation{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for proposal := range proposals {
+				evaluation, err := fo.evaluateTrade(rosters, before, proposal)
+				if err != nil {
+					// e.g. a 2-for-1 which leaves someone with too many players.
+					continue
+				}
+				if !paretoImprovement(evaluation) {
+					continue
+				}
+				mu.Lock()
+				results = append(results, evaluation)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	rankTrades(results)
+	if options.MaxResults > 0 && len(results) > options.MaxResults {
+		results = results[:options.MaxResults]
+	}
+	return results, nil
+}
+
+// Whether a trade helps the first team, without hurting the second.
+func paretoImprovement(evaluation *TradeEvaluation) bool {
+	a, b := evaluation.Proposal.A.Team, evaluation.Proposal.B.Team
+	return evaluation.Deltas[a] > 0 && evaluation.Deltas[b] >= 0
+}
+
+func rankTrades(evaluations []*TradeEvaluation) {
+	sort.SliceStable(evaluations, func(i, j int) bool {
+		x, y := evaluations[i], evaluations[j]
+		xa, ya := x.Deltas[x.Proposal.A.Team], y.Deltas[y.Proposal.A.Team]
+		if xa != ya {
+			return xa > ya
+		}
+		xb, yb := x.Deltas[x.Proposal.B.Team], y.Deltas[y.Proposal.B.Team]
+		if xb != yb {
+			return xb > yb
+		}
+		return x.Proposal.String() < y.Proposal.String()
+	})
+}
+
+// How valuable each rostered player is, relative to every other rostered
+// player in the league, keyed by Yahoo player key.
+func (fo *FO) playerValues(rosters map[TeamID][]YahooPlayer) map[string]float32 {
+	stats := make(map[PlayerID]StatLine)
+	for _, roster := range rosters {
+		for _, player := range roster {
+			stats[PlayerID(player.PlayerKey)] = fo.projections.GetStatLine(fo.playerID(player))
+		}
+	}
+
+	values := make(map[string]float32)
+	for key, value := range scoreTeam(stats, fo.settings.Scorer()) {
+		values[string(key)] = value
+	}
+	return values
+}
+
+// A team's active players, most valuable first, limited to the given number
+// if it's positive.
+func tradeCandidates(roster []YahooPlayer, values map[string]float32, limit int) []YahooPlayer {
+	candidates := []YahooPlayer{}
+	for _, player := range roster {
+		if !isInactive(player) {
+			candidates = append(candidates, player)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return values[candidates[i].PlayerKey] > values[candidates[j].PlayerKey]
+	})
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates
+}
+
+// Every single player, and every pair of players.
+func playerCombinations(players []YahooPlayer) [][]YahooPlayer {
+	combinations := [][]YahooPlayer{}
+	for i := range players {
+		combinations = append(combinations, []YahooPlayer{players[i]})
+	}
+	for i := range players {
+		for j := i + 1; j < len(players); j++ {
+			combinations = append(combinations, []YahooPlayer{players[i], players[j]})
+		}
+	}
+	return combinations
+}
+
+// How to refer to players in a trade: by name, unless someone else on the
+// roster has the same name.
+func tradeRefs(roster []YahooPlayer, players []YahooPlayer) []PlayerID {
+	names := make(map[string]int)
+	for _, player := range roster {
+		names[player.FullName]++
+	}
+
+	refs := make([]PlayerID, len(players))
+	for i, player := range players {
+		if names[player.FullName] > 1 {
+			refs[i] = PlayerID(player.PlayerKey)
+		} else {
+			refs[i] = PlayerID(player.FullName)
+		}
+	}
+	return refs
+}
+
+// Adds drops to a proposal for any team which ends up with more players than
+// its roster has room for.  Each team drops whichever of its remaining
+// players is least valuable.
+func (fo *FO) withDrops(rosters map[TeamID][]YahooPlayer, values map[string]float32, proposal TradeProposal) TradeProposal {
+	limit := fo.settings.Topology.ActiveSize()
+	if limit <= 0 {
+		return proposal
+	}
+
+	sides := []*TradeSide{&proposal.A, &proposal.B}
+	for i, side := range sides {
+		other := sides[1-i]
+		extra := len(other.Gives) - len(side.Gives)
+		roster := rosters[side.Team]
+		if extra <= 0 || activeCount(roster)+extra <= limit {
+			continue
+		}
+
+		gives := make(map[int]bool)
+		for _, ref := range side.Gives {
+			for _, j := range findPlayer(roster, ref, fo.playerID) {
+				gives[j] = true
+			}
+		}
+		remaining := []YahooPlayer{}
+		for j, player := range roster {
+			if !gives[j] {
+				remaining = append(remaining, player)
+			}
+		}
+
+		candidates := tradeCandidates(remaining, values, 0)
+		drops := min(activeCount(roster)+extra-limit, len(candidates))
+		worst := candidates[len(candidates)-drops:]
+		side.Drops = tradeRefs(roster, worst)
+	}
+	return proposal
+}
@@ -0,0 +1,120 @@
+package folib
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFindTrades(t *testing.T) {
+	categories := ScoringCategories{
+		B_HOME_RUNS:    HIGHER_IS_BETTER,
+		B_STOLEN_BASES: HIGHER_IS_BETTER,
+	}
+	fo := testFO(fakeStatsClient{
+		"Big Bopper":   {B_HOME_RUNS: 45},
+		"Small Bopper": {B_HOME_RUNS: 35},
+		"Big Burner":   {B_STOLEN_BASES: 45},
+		"Small Burner": {B_STOLEN_BASES: 35},
+		"Average Joe":  {B_HOME_RUNS: 15, B_STOLEN_BASES: 15},
+		"Average Jim":  {B_HOME_RUNS: 15, B_STOLEN_BASES: 15},
+	}, &LeagueSettings{
+		Categories: categories,
+		Topology:   RosterTopology{Starters: map[Position]int{"OF": 2}},
+	})
+	fo.league = &LeagueContext{MyTeamID: 1}
+
+	// Everyone is tied, but teams 1 and 2 have more than they need of one
+	// category each.
+	rosters := map[TeamID][]YahooPlayer{
+		1: {hitter("p.1", "Big Bopper"), hitter("p.2", "Small Bopper")},
+		2: {hitter("p.3", "Big Burner"), hitter("p.4", "Small Burner")},
+		3: {hitter("p.5", "Average Joe"), hitter("p.6", "Average Jim")},
+	}
+
+	trades, err := fo.FindTrades(context.Background(), rosters, TradeSearchOptions{Workers: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) == 0 {
+		t.Fatalf("Expected to find some trades")
+	}
+
+	for _, trade := range trades {
+		if trade.Proposal.A.Team != 1 {
+			t.Errorf("Expected every trade to be from my team: %s", trade.Proposal)
+		}
+		if !paretoImprovement(trade) {
+			t.Errorf("Expected only trades that help both teams: %s (%v)", trade.Proposal, trade.Deltas)
+		}
+	}
+
+	// Getting the better base stealer for the worse slugger wins me both
+	// categories, and doesn't cost team 2 anything.
+	best := trades[0]
+	if best.Proposal.String() != "TEAM 1 gives Small Bopper; TEAM 2 gives Big Burner" {
+		t.Errorf("Wrong best trade: %s", best.Proposal)
+	}
+	if best.Deltas[1] != 2 || best.Deltas[2] != 0 || best.Deltas[3] != -2 {
+		t.Errorf("Expected +2/+0/-2, got: %v", best.Deltas)
+	}
+
+	found := false
+	for _, trade := range trades {
+		if trade.Proposal.String() == "TEAM 1 gives Small Bopper; TEAM 2 gives Small Burner" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected the even swap of small players, got: %v", trades)
+	}
+
+	limited, err := fo.FindTrades(context.Background(), rosters, TradeSearchOptions{MaxResults: 1, PlayersPerTeam: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limited) != 1 {
+		t.Errorf("Expected one result, got: %d", len(limited))
+	}
+}
+
+func TestWithDrops(t *testing.T) {
+	fo := testFO(nil, &LeagueSettings{
+		Topology: RosterTopology{Starters: map[Position]int{"OF": 2}},
+	})
+	rosters := testRosters()
+	values := map[string]float32{"p.1": 5, "p.2": 4, "p.3": 1, "p.4": 6, "p.5": 3, "p.6": 2}
+
+	// Team 1 only has room for two players, so after getting two for one
+	// it has to drop both of its remaining players.
+	proposal := fo.withDrops(rosters, values, TradeProposal{
+		A: TradeSide{Team: 1, Gives: []PlayerID{"Slugger"}},
+		B: TradeSide{Team: 2, Gives: []PlayerID{"Masher", "p.5"}},
+	})
+	if len(proposal.A.Drops) != 2 || proposal.A.Drops[0] != "Speedster" || proposal.A.Drops[1] != "Scrub" {
+		t.Errorf("Expected team 1 to drop Speedster and Scrub, got: %v", proposal.A.Drops)
+	}
+	if len(proposal.B.Drops) != 0 {
+		t.Errorf("Team 2 shouldn't need to drop anyone, got: %v", proposal.B.Drops)
+	}
+}
+
+func TestWithPlayerIDs(t *testing.T) {
+	fo := &FO{
+		players: NewPlayerResolver([]PlayerRecord{
+			{ID: "Big Bopper [DET]", Name: "Big Bopper", Team: "DET", Side: BATTING},
+		}, []PlayerOverride{}),
+	}
+	rosters := map[TeamID][]YahooPlayer{
+		1: {hitter("p.1", "Big Bopper")},
+	}
+
+	search := fo.withPlayerIDs(rosters)
+	// Nobody left to ask.
+	search.players = nil
+	if id := search.playerID(rosters[1][0]); id != "Big Bopper [DET]" {
+		t.Errorf("Expected the id resolved up front, got: %s", id)
+	}
+	if fo.playerIDs != nil {
+		t.Errorf("Expected the original to be left alone")
+	}
+}
@@ -0,0 +1,132 @@
+package folib
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+)
+
+// A roster move, as Yahoo reports it back to us.
+type YahooTransaction struct {
+	TransactionKey string `xml:"transaction_key"`
+	Type           string `xml:"type"`   // e.g. "add", "drop", "add/drop"
+	Status         string `xml:"status"` // e.g. "successful", or "pending" for waiver claims
+	FaabBid        int    `xml:"faab_bid"`
+	Timestamp      int64  `xml:"timestamp"`
+
+	Players []YahooTransactionPlayer `xml:"players>player"`
+}
+
+type YahooTransactionPlayer struct {
+	PlayerKey string               `xml:"player_key"`
+	FullName  string               `xml:"name>full"`
+	Data      YahooTransactionData `xml:"transaction_data"`
+}
+
+type YahooTransactionData struct {
+	Type               string `xml:"type"`
+	SourceTeamKey      string `xml:"source_team_key,omitempty"`
+	DestinationTeamKey string `xml:"destination_team_key,omitempty"`
+}
+
+// Adds a free agent to a team.
+func (yc *YahooClient) AddPlayer(ctx context.Context, leagueKey, teamKey, playerKey string) (*YahooTransaction, error) {
+	return yc.transact(ctx, leagueKey, newTransaction(teamKey, playerKey, "", nil))
+}
+
+// Drops a player from a team.
+func (yc *YahooClient) DropPlayer(ctx context.Context, leagueKey, teamKey, playerKey string) (*YahooTransaction, error) {
+	return yc.transact(ctx, leagueKey, newTransaction(teamKey, "", playerKey, nil))
+}
+
+// Adds a free agent to a team, and drops another player to make room.
+func (yc *YahooClient) AddDrop(ctx context.Context, leagueKey, teamKey, addKey, dropKey string) (*YahooTransaction, error) {
+	return yc.transact(ctx, leagueKey, newTransaction(teamKey, addKey, dropKey, nil))
+}
+
+// Puts in a claim for a player on waivers, bidding faabBid of the team's
+// free agent budget (in leagues that have one).  dropKey may be empty if
+// there's room on the roster.  Claims come back "pending" until waivers
+// clear.
+func (yc *YahooClient) WaiverClaim(ctx context.Context, leagueKey, teamKey, addKey, dropKey string, faabBid int) (*YahooTransaction, error) {
+	return yc.transact(ctx, leagueKey, newTransaction(teamKey, addKey, dropKey, &faabBid))
+}
+
+type transactionRequest struct {
+	XMLName xml.Name            `xml:"fantasy_content"`
+	Type    string              `xml:"transaction>type"`
+	FaabBid *int                `xml:"transaction>faab_bid,omitempty"`
+	Players []transactionPlayer `xml:"transaction>players>player"`
+}
+
+type transactionPlayer struct {
+	PlayerKey string               `xml:"player_key"`
+	Data      YahooTransactionData `xml:"transaction_data"`
+}
+
+func newTransaction(teamKey, addKey, dropKey string, faabBid *int) transactionRequest {
+	request := transactionRequest{FaabBid: faabBid}
+	if addKey != "" {
+		request.Players = append(request.Players, transactionPlayer{
+			PlayerKey: addKey,
+			Data:      YahooTransactionData{Type: "add", DestinationTeamKey: teamKey},
+		})
+	}
+	if dropKey != "" {
+		request.Players = append(request.Players, transactionPlayer{
+			PlayerKey: dropKey,
+			Data:      YahooTransactionData{Type: "drop", SourceTeamKey: teamKey},
+		})
+	}
+
+	switch {
+	case addKey != "" && dropKey != "":
+		request.Type = "add/drop"
+	case addKey != "":
+		request.Type = "add"
+	default:
+		request.Type = "drop"
+	}
+	return request
+}
+
+func (yc *YahooClient) transact(ctx context.Context, leagueKey string, request transactionRequest) (*YahooTransaction, error) {
+	if len(request.Players) == 0 {
+		return nil, fmt.Errorf("A transaction needs a player to add or drop")
+	}
+
+	body, err := transactionXml(request)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/league/%s/transactions", yc.apiUrl, leagueKey)
+	response, err := yc.send(ctx, "POST", url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseTransaction(response)
+}
+
+func transactionXml(request transactionRequest) (string, error) {
+	bits, err := xml.MarshalIndent(request, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(bits), nil
+}
+
+func parseTransaction(response string) (*YahooTransaction, error) {
+	var data struct {
+		Transaction YahooTransaction `xml:"transaction"`
+	}
+	err := xml.Unmarshal([]byte(response), &data)
+	if err != nil {
+		return nil, err
+	}
+	if data.Transaction.Status == "" {
+		return nil, fmt.Errorf("No transaction status in response: %s", response)
+	}
+	return &data.Transaction, nil
+}
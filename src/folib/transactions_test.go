@@ -0,0 +1,208 @@
+package folib
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const transactionsPath = "/league/328.l.1/transactions"
+
+func transactionResponse(kind, status string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<fantasy_content>
+  <transaction>
+    <transaction_key>328.l.1.tr.42</transaction_key>
+    <type>` + kind + `</type>
+    <status>` + status + `</status>
+    <timestamp>1399000000</timestamp>
+  </transaction>
+</fantasy_content>`
+}
+
+func TestAddPlayer(t *testing.T) {
+	yahoo, client := newFakeYahoo(t)
+	yahoo.respondTo("POST", transactionsPath, transactionResponse("add", "successful"))
+
+	transaction, err := client.AddPlayer(context.Background(), "328.l.1", "328.l.1.t.5", "328.p.100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transaction.Status != "successful" || transaction.TransactionKey != "328.l.1.tr.42" {
+		t.Errorf("Wrong transaction: %v", transaction)
+	}
+
+	expectWrite(t, yahoo, `<?xml version="1.0" encoding="UTF-8"?>
+<fantasy_content>
+  <transaction>
+    <type>add</type>
+    <players>
+      <player>
+        <player_key>328.p.100</player_key>
+        <transaction_data>
+          <type>add</type>
+          <destination_team_key>328.l.1.t.5</destination_team_key>
+        </transaction_data>
+      </player>
+    </players>
+  </transaction>
+</fantasy_content>`)
+}
+
+func TestDropPlayer(t *testing.T) {
+	yahoo, client := newFakeYahoo(t)
+	yahoo.respondTo("POST", transactionsPath, transactionResponse("drop", "successful"))
+
+	_, err := client.DropPlayer(context.Background(), "328.l.1", "328.l.1.t.5", "328.p.200")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectWrite(t, yahoo, `<?xml version="1.0" encoding="UTF-8"?>
+<fantasy_content>
+  <transaction>
+    <type>drop</type>
+    <players>
+      <player>
+        <player_key>328.p.200</player_key>
+        <transaction_data>
+          <type>drop</type>
+          <source_team_key>328.l.1.t.5</source_team_key>
+        </transaction_data>
+      </player>
+    </players>
+  </transaction>
+</fantasy_content>`)
+}
+
+func TestAddDrop(t *testing.T) {
+	yahoo, client := newFakeYahoo(t)
+	yahoo.respondTo("POST", transactionsPath, transactionResponse("add/drop", "successful"))
+
+	transaction, err := client.AddDrop(context.Background(), "328.l.1", "328.l.1.t.5", "328.p.100", "328.p.200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transaction.Type != "add/drop" {
+		t.Errorf("Wrong transaction type: %s", transaction.Type)
+	}
+
+	expectWrite(t, yahoo, `<?xml version="1.0" encoding="UTF-8"?>
+<fantasy_content>
+  <transaction>
+    <type>add/drop</type>
+    <players>
+      <player>
+        <player_key>328.p.100</player_key>
+        <transaction_data>
+          <type>add</type>
+          <destination_team_key>328.l.1.t.5</destination_team_key>
+        </transaction_data>
+      </player>
+      <player>
+        <player_key>328.p.200</player_key>
+        <transaction_data>
+          <type>drop</type>
+          <source_team_key>328.l.1.t.5</source_team_key>
+        </transaction_data>
+      </player>
+    </players>
+  </transaction>
+</fantasy_content>`)
+}
+
+func TestWaiverClaim(t *testing.T) {
+	yahoo, client := newFakeYahoo(t)
+	yahoo.respondTo("POST", transactionsPath, transactionResponse("add/drop", "pending"))
+
+	transaction, err := client.WaiverClaim(context.Background(), "328.l.1", "328.l.1.t.5", "328.p.100", "328.p.200", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transaction.Status != "pending" {
+		t.Errorf("Expected a pending claim, got: %s", transaction.Status)
+	}
+
+	// A $0 bid is still a bid.
+	expectWrite(t, yahoo, `<?xml version="1.0" encoding="UTF-8"?>
+<fantasy_content>
+  <transaction>
+    <type>add/drop</type>
+    <faab_bid>0</faab_bid>
+    <players>
+      <player>
+        <player_key>328.p.100</player_key>
+        <transaction_data>
+          <type>add</type>
+          <destination_team_key>328.l.1.t.5</destination_team_key>
+        </transaction_data>
+      </player>
+      <player>
+        <player_key>328.p.200</player_key>
+        <transaction_data>
+          <type>drop</type>
+          <source_team_key>328.l.1.t.5</source_team_key>
+        </transaction_data>
+      </player>
+    </players>
+  </transaction>
+</fantasy_content>`)
+}
+
+func TestTransactionRejected(t *testing.T) {
+	yahoo, client := newFakeYahoo(t)
+	yahoo.respondWithStatus("POST", transactionsPath, http.StatusBadRequest,
+		`<?xml version="1.0"?><error><description>Player is not available</description></error>`)
+
+	_, err := client.AddPlayer(context.Background(), "328.l.1", "328.l.1.t.5", "328.p.100")
+	if err == nil || !strings.Contains(err.Error(), "Player is not available") {
+		t.Errorf("Expected Yahoo's error, got: %v", err)
+	}
+
+	_, err = client.AddDrop(context.Background(), "328.l.1", "328.l.1.t.5", "", "")
+	if err == nil {
+		t.Errorf("Expected an error for an empty transaction")
+	}
+	if len(yahoo.writes()) != 1 {
+		t.Errorf("Expected the empty transaction not to be sent, got: %v", yahoo.writes())
+	}
+}
+
+func expectWrite(t *testing.T, yahoo *fakeYahoo, body string) {
+	t.Helper()
+	writes := yahoo.writes()
+	if len(writes) != 1 {
+		t.Fatalf("Expected one write, got: %v", writes)
+	}
+	if writes[0].Method != "POST" || writes[0].Path != transactionsPath {
+		t.Errorf("Expected a POST to %s, got: %s %s", transactionsPath, writes[0].Method, writes[0].Path)
+	}
+	if writes[0].Body != body {
+		t.Errorf("Expected:\n%s\nGot:\n%s", body, writes[0].Body)
+	}
+}
+
+func TestTransactionRetriedOnlyWhenThrottled(t *testing.T) {
+	yahoo, client := newFakeYahoo(t)
+	yahoo.respondTo("POST", transactionsPath, transactionResponse("add", "successful"))
+
+	// Yahoo turns throttled requests away without acting on them.
+	yahoo.queue("POST", transactionsPath, YAHOO_THROTTLED, "Request denied")
+	if _, err := client.AddPlayer(context.Background(), "328.l.1", "328.l.1.t.5", "328.p.100"); err != nil {
+		t.Fatal(err)
+	}
+	if len(yahoo.writes()) != 2 {
+		t.Errorf("Expected the throttled add to be retried, got: %v", yahoo.writes())
+	}
+
+	// But an add that failed some other way might have gone through.
+	yahoo.queue("POST", transactionsPath, http.StatusInternalServerError, "")
+	_, err := client.AddPlayer(context.Background(), "328.l.1", "328.l.1.t.5", "328.p.100")
+	if yahooErr, ok := err.(*YahooError); !ok || yahooErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected Yahoo's error, got: %v", err)
+	}
+	if len(yahoo.writes()) != 3 {
+		t.Errorf("Expected the failed add not to be retried, got: %v", yahoo.writes())
+	}
+}
@@ -1,63 +1,133 @@
 package folib
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math"
+	"net/http"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
-
-	"github.com/mrjones/oauth"
 )
 
 //
 // API
 //
 
-func NewYahooClient(consumerKey, consumerSecret, tokenFile string) *YahooClient {
+const YAHOO_API_URL = "https://fantasysports.yahooapis.com/fantasy/v2"
+
+// A client which authorizes with Yahoo's OAuth2 flow, pasting the
+// authorization code back in from the browser, and keeps its tokens in
+// tokenFile.
+func NewYahooClient(clientID, clientSecret, tokenFile string) *YahooClient {
+	return NewYahooClientWithTokens(
+		NewYahooTokenProvider(clientID, clientSecret, OOB_REDIRECT, tokenFile))
+}
+
+func NewYahooClientWithTokens(tokens TokenProvider) *YahooClient {
 	return &YahooClient{
-		tokenFile: tokenFile,
-		oauth: oauth.NewConsumer(
-			consumerKey,
-			consumerSecret,
-			oauth.ServiceProvider{
-				RequestTokenUrl:   "https://api.login.yahoo.com/oauth/v2/get_request_token",
-				AuthorizeTokenUrl: "https://api.login.yahoo.com/oauth/v2/request_auth",
-				AccessTokenUrl:    "https://api.login.yahoo.com/oauth/v2/get_token",
-			}),
-		cache: NewReadThroughCache(NewFileKVStore("./cache")),
-	}
-}
-
-func (yc *YahooClient) Get(url string) (string, error) {
-//	fmt.Printf("Getting '%s'\n", url)
-	token, err := yc.getAccessToken()
-	if err != nil {
-		return "", err
+		tokens:  tokens,
+		client:  http.DefaultClient,
+		cache:   NewReadThroughCache(NewFileKVStore("./cache")),
+		apiUrl:  YAHOO_API_URL,
+		limiter: NewTokenBucket(YAHOO_REQUESTS_PER_SECOND, YAHOO_REQUEST_BURST),
+		retry:   DEFAULT_RETRY_POLICY,
 	}
+}
 
-	response, err := yc.oauth.Get(
-		url,
-		map[string]string{},
-		token)
+// Shares a rate limiter with other clients, e.g. to keep several leagues'
+// worth of requests under Yahoo's limit.  A nil limiter turns limiting off.
+func (yc *YahooClient) SetLimiter(limiter *TokenBucket) {
+	yc.limiter = limiter
+}
 
-	if err != nil {
-		return "", err
+// Fetches a URL, returning a *YahooError if Yahoo doesn't accept the request.
+func (yc *YahooClient) Get(ctx context.Context, url string) (string, error) {
+	return yc.request(ctx, "GET", url, "")
+}
+
+// Makes an authorized request to Yahoo, waiting on the rate limiter first.
+// If Yahoo rejects our access token, we get a new one and try once more.  If
+// it's throttling us or having trouble, we back off and retry, as long as
+// that can't repeat a change: writes are only retried when throttled.
+func (yc *YahooClient) do(ctx context.Context, method, url, body string) (*http.Response, error) {
+	refreshed := false
+	for failures := 0; ; {
+		token, err := yc.tokens.Token(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := yc.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		response, err := yc.authorizedRequest(ctx, method, url, body, token)
+		if err == nil && response.StatusCode == http.StatusUnauthorized && !refreshed {
+			response.Body.Close()
+			if _, err := yc.tokens.Refresh(ctx, token); err != nil {
+				return nil, err
+			}
+			refreshed = true
+			continue
+		}
+		if ctx.Err() != nil {
+			if err == nil {
+				response.Body.Close()
+			}
+			return nil, ctx.Err()
+		}
+
+		failures++
+		if failures >= yc.retry.MaxAttempts || !retryable(method, response, err) {
+			return response, err
+		}
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = response.Status
+			response.Body.Close()
+		}
+		delay := yc.retry.backoff(failures - 1)
+		log.Printf("%s: '%s' failed (%s), retrying in %v", method, url, reason, delay)
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
 	}
-	defer response.Body.Close()
+}
 
-	bits, err := ioutil.ReadAll(response.Body)
+// Whether a failed request is worth trying again.  Yahoo turns throttled
+// requests away before acting on them, but a write which failed any other
+// way may have gone through, so only GETs and PUTs (which set a lineup to
+// the same thing again) are retried then.
+func retryable(method string, response *http.Response, err error) bool {
+	idempotent := method == "GET" || method == "PUT"
 	if err != nil {
-		return "", err
+		return idempotent
 	}
+	return response.StatusCode == YAHOO_THROTTLED ||
+		(idempotent && response.StatusCode >= 500)
+}
 
-	return string(bits), nil
+func (yc *YahooClient) authorizedRequest(ctx context.Context, method, url, body string, token *OAuth2Token) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	if body != "" {
+		request.Header.Set("Content-Type", "application/xml")
+	}
+	return yc.client.Do(request)
 }
 
-func (yc *YahooClient) GetGames() ([]YahooGame, error) {
-	body, err := yc.Get("http://fantasysports.yahooapis.com/fantasy/v2/game/mlb")
+func (yc *YahooClient) GetGames(ctx context.Context) ([]YahooGame, error) {
+	body, err := yc.Get(ctx, yc.apiUrl+"/game/mlb")
 	if err != nil {
 		return []YahooGame{}, err
 	}
@@ -71,9 +141,9 @@ func (yc *YahooClient) GetGames() ([]YahooGame, error) {
 	return data.Games, nil
 }
 
-func (yc *YahooClient) GetLeagues(gameKey string) ([]YahooLeague, error) {
-	url := fmt.Sprintf("http://fantasysports.yahooapis.com/fantasy/v2/users;use_login=1/games;game_keys=%s/leagues", gameKey)
-	body, err := yc.Get(url)
+func (yc *YahooClient) GetLeagues(ctx context.Context, gameKey string) ([]YahooLeague, error) {
+	url := fmt.Sprintf("%s/users;use_login=1/games;game_keys=%s/leagues", yc.apiUrl, gameKey)
+	body, err := yc.Get(ctx, url)
 	if err != nil {
 		return []YahooLeague{}, err
 	}
@@ -99,10 +169,10 @@ type listTeamsReply struct {
 	Teams []YahooTeam `xml:"leagues>league>teams>team"`
 }
 
-func (yc* YahooClient) GetTeams(leagueKey string) ([]YahooTeam, error) {
-	url := fmt.Sprintf("http://fantasysports.yahooapis.com/fantasy/v2/leagues;league_keys=%s/teams", leagueKey)
+func (yc* YahooClient) GetTeams(ctx context.Context, leagueKey string) ([]YahooTeam, error) {
+	url := fmt.Sprintf("%s/leagues;league_keys=%s/teams", yc.apiUrl, leagueKey)
 
-	body, err := yc.Get(url)
+	body, err := yc.Get(ctx, url)
 	if err != nil {
 		return []YahooTeam{}, err
 	}
@@ -116,14 +186,31 @@ func (yc* YahooClient) GetTeams(leagueKey string) ([]YahooTeam, error) {
 	return data.Teams, nil
 }
 
+func (yc *YahooClient) GetLeagueSettings(ctx context.Context, leagueKey string) (*LeagueSettings, error) {
+	url := fmt.Sprintf("%s/league/%s/settings", yc.apiUrl, leagueKey)
+
+	body, err := yc.Get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var data FantasyContent
+	err = xml.Unmarshal([]byte(body), &data)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseLeagueSettings(data.League)
+}
+
 type getRosterReply struct {
 	Players []YahooPlayer `xml:"team>roster>players>player"`
 }
 
-func (yc* YahooClient) GetRoster(teamKey string) ([]YahooPlayer, error) {
-	url := fmt.Sprintf("http://fantasysports.yahooapis.com/fantasy/v2/team/%s/roster", teamKey)
+func (yc* YahooClient) GetRoster(ctx context.Context, teamKey string) ([]YahooPlayer, error) {
+	url := fmt.Sprintf("%s/team/%s/roster", yc.apiUrl, teamKey)
 
-	body, err := yc.Get(url)
+	body, err := yc.Get(ctx, url)
 	if err != nil {
 		return []YahooPlayer{}, err
 	}
@@ -137,6 +224,91 @@ func (yc* YahooClient) GetRoster(teamKey string) ([]YahooPlayer, error) {
 	return data.Players, nil	
 }
 
+// Fetches a team's roster as of a date (e.g. "2014-05-01"), including where
+// each player is slotted in the lineup that day.
+func (yc *YahooClient) GetLineup(ctx context.Context, teamKey, date string) ([]YahooPlayer, error) {
+	url := fmt.Sprintf("%s/team/%s/roster;date=%s", yc.apiUrl, teamKey, date)
+
+	body, err := yc.Get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var data getRosterReply
+	err = xml.Unmarshal([]byte(body), &data)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.Players, nil
+}
+
+type setLineupRequest struct {
+	XMLName      xml.Name              `xml:"fantasy_content"`
+	CoverageType string                `xml:"roster>coverage_type"`
+	Date         string                `xml:"roster>date"`
+	Players      []setLineupAssignment `xml:"roster>players>player"`
+}
+
+type setLineupAssignment struct {
+	PlayerKey string   `xml:"player_key"`
+	Position  Position `xml:"position"`
+}
+
+// Moves players (by player key) into the given lineup slots on a date.
+// Players who aren't mentioned stay where they are.
+func (yc *YahooClient) SetLineup(ctx context.Context, teamKey, date string, assignments map[string]Position) error {
+	body, err := lineupXml(date, assignments)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/team/%s/roster", yc.apiUrl, teamKey)
+	_, err = yc.send(ctx, "PUT", url, body)
+	return err
+}
+
+func lineupXml(date string, assignments map[string]Position) (string, error) {
+	request := setLineupRequest{CoverageType: "date", Date: date}
+	for key, position := range assignments {
+		request.Players = append(request.Players, setLineupAssignment{PlayerKey: key, Position: position})
+	}
+	sort.Slice(request.Players, func(i, j int) bool {
+		return request.Players[i].PlayerKey < request.Players[j].PlayerKey
+	})
+
+	bits, err := xml.MarshalIndent(request, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(bits), nil
+}
+
+// Sends a request to Yahoo, e.g. an XML body to make a change, failing with
+// a *YahooError if Yahoo doesn't accept it.
+func (yc *YahooClient) send(ctx context.Context, method, url, body string) (string, error) {
+	log.Printf("%s: '%s'", method, url)
+	return yc.request(ctx, method, url, body)
+}
+
+func (yc *YahooClient) request(ctx context.Context, method, url, body string) (string, error) {
+	response, err := yc.do(ctx, method, url, body)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	bits, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return "", parseYahooError(method, url, response, bits)
+	}
+	return string(bits), nil
+}
+
 type getStatsPlayer struct {
 	Stats []YahooStat `xml:"player_stats>stats>stat"`
 	PlayerKey string `xml:"player_key"`
@@ -147,120 +319,223 @@ type getStatsReply struct {
 	Players []getStatsPlayer `xml:"players>player"`
 }
 
-func (yc* YahooClient) GetStats(playerKeys []string) (map[string]StatLine, error) {
+// Which stats GetStats fetches.  The zero value is the season so far.
+type StatsScope struct {
+	// A week of the fantasy season, counting from 1.
+	Week int
+	// A single day, e.g. "2014-05-01".
+	Date string
+}
+
+func (s StatsScope) path() (string, error) {
+	switch {
+	case s.Week != 0 && s.Date != "":
+		return "", fmt.Errorf("Stats can be for a week or a date, not both")
+	case s.Week < 0:
+		return "", fmt.Errorf("No such week: %d", s.Week)
+	case s.Week > 0:
+		return fmt.Sprintf(";type=week;week=%d", s.Week), nil
+	case s.Date != "":
+		if _, err := time.Parse("2006-01-02", s.Date); err != nil {
+			return "", err
+		}
+		return ";type=date;date=" + s.Date, nil
+	}
+	return "", nil
+}
+
+const (
+	// Yahoo won't return more than 25 players' stats per request.
+	STATS_PLAYERS_PER_REQUEST = 20
+	// How many requests for stats to make at once.  They still wait their
+	// turn with the rate limiter.
+	STATS_WORKERS = 4
+)
+
+// The batches of players GetStats couldn't get stats for, and why.
+type StatsError struct {
+	// How many batches were fetched in all.
+	Batches  int
+	Failures []StatsBatchError
+}
+
+type StatsBatchError struct {
+	PlayerKeys []string
+	Err        error
+}
+
+func (e *StatsError) Error() string {
+	reasons := []string{}
+	for _, failure := range e.Failures {
+		reasons = append(reasons, failure.Err.Error())
+	}
+	return fmt.Sprintf("Couldn't get stats for %d of %d batches of players: %s",
+		len(e.Failures), e.Batches, strings.Join(reasons, "; "))
+}
+
+func (e *StatsError) Unwrap() []error {
+	errs := []error{}
+	for _, failure := range e.Failures {
+		errs = append(errs, failure.Err)
+	}
+	return errs
+}
+
+// Fetches players' stats, a batch of players at a time, several batches at
+// once.  If some batches fail, the stats from the rest are returned with a
+// *StatsError saying which.
+func (yc *YahooClient) GetStats(ctx context.Context, playerKeys []string, scope StatsScope) (map[string]StatLine, error) {
 	result := make(map[string]StatLine)
+	scopePath, err := scope.path()
+	if err != nil {
+		return result, err
+	}
 
-	MAX_IDS_PER_REQUEST := 20 // Yahoo won't return more than 25 per request
+	batches := [][]string{}
+	for start := 0; start < len(playerKeys); start += STATS_PLAYERS_PER_REQUEST {
+		batches = append(batches, playerKeys[start:min(start+STATS_PLAYERS_PER_REQUEST, len(playerKeys))])
+	}
 
-	windowStart := 0
+	lines := make([]map[string]StatLine, len(batches))
+	errs := make([]error, len(batches))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < min(STATS_WORKERS, len(batches)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range next {
+				lines[batch], errs[batch] = yc.getStatsBatch(ctx, batches[batch], scopePath)
+			}
+		}()
+	}
+	for batch := range batches {
+		next <- batch
+	}
+	close(next)
+	wg.Wait()
 
-	for {
-		if windowStart >= len(playerKeys) {
-			break
+	failed := &StatsError{Batches: len(batches)}
+	for batch, err := range errs {
+		if err != nil {
+			failed.Failures = append(failed.Failures, StatsBatchError{PlayerKeys: batches[batch], Err: err})
+			continue
 		}
-		windowSize := MAX_IDS_PER_REQUEST
-		if (len(playerKeys) - windowStart < windowSize) {
-			windowSize = len(playerKeys) - windowStart
+		for key, line := range lines[batch] {
+			result[key] = line
 		}
-		window := playerKeys[windowStart:windowStart+windowSize]
-		windowStart += windowSize
+	}
+	if len(failed.Failures) > 0 {
+		return result, failed
+	}
+	return result, nil
+}
 
-		url := fmt.Sprintf("http://fantasysports.yahooapis.com/fantasy/v2/players;player_keys=%s/stats", strings.Join(window, ","))
+func (yc *YahooClient) getStatsBatch(ctx context.Context, playerKeys []string, scopePath string) (map[string]StatLine, error) {
+	url := fmt.Sprintf("%s/players;player_keys=%s/stats%s", yc.apiUrl, strings.Join(playerKeys, ","), scopePath)
 
-		body, err := yc.Get(url)
-		if err != nil {
-			return result, err
-		}
+	body, err := yc.Get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
 
-		var data getStatsReply
-		err = xml.Unmarshal([]byte(body), &data)
-		if err != nil {
-			return result, err
-		}
-
-		yahooIdToStatIdMap := mapYahooIdToStatId()
-		for _, player := range(data.Players) {
-			statline := StatLine{}
-			for _, ystat := range(player.Stats) {
-				statid, ok := yahooIdToStatIdMap[ystat.ID]
-				if ok {
-					statval, err := strconv.ParseFloat(ystat.Value, 64)
-					if err == nil {
-						statline[statid] = Stat(statval)
+	var data getStatsReply
+	err = xml.Unmarshal([]byte(body), &data)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]StatLine)
+	for _, player := range data.Players {
+		statline := StatLine{}
+		for _, ystat := range player.Stats {
+			statid, ok := statIdByYahooId[ystat.ID]
+			if ok {
+				statval, err := strconv.ParseFloat(ystat.Value, 64)
+				if err == nil {
+					if statid == P_INNINGS {
+						statval = fromBaseballInnings(statval)
 					}
+					statline[statid] = Stat(statval)
 				}
 			}
-			result[player.PlayerKey] = statline
 		}
+		result[player.PlayerKey] = statline
 	}
-
 	return result, nil
 }
 
-// old api (hardcoded)
-
-func (yc *YahooClient) CurrentStats() (*map[TeamID]StatLine, error) {
-	response, err := yc.cacheGet(
-		"current_stats",
-		"http://fantasysports.yahooapis.com/fantasy/v2/league/308.l.21006/standings")
-//		"http://fantasysports.yahooapis.com/fantasy/v2/league/mlb.l.5181/standings")
+func (yc *YahooClient) CurrentStats(ctx context.Context, leagueKey string) (*map[TeamID]StatLine, error) {
+	response, err := yc.cacheGet(ctx,
+		"current_stats_"+leagueKey,
+		fmt.Sprintf("%s/league/%s/standings", yc.apiUrl, leagueKey))
 
 	if err != nil {
 		return nil, err
 	}
 
-	//	fmt.Println(response)
-
 	var data FantasyContent
 	err = xml.Unmarshal([]byte(response), &data)
 	if err != nil {
 		return nil, err
 	}
 
-	yahooIdToStatIdMap := mapYahooIdToStatId()
-
 	teamstats := map[TeamID]StatLine{}
 
 	for i := range data.League.Teams {
 		team := data.League.Teams[i]
-		statline := make(StatLine)
-		for j := range team.Stats {
-			stat := team.Stats[j]
-			statid, ok := yahooIdToStatIdMap[stat.ID]
-			if ok {
-				statval, err := strconv.ParseFloat(stat.Value, 64)
-				if err != nil {
-					return nil, err
-				}
-				statline[statid] = Stat(statval)
-			}
+		statline, err := parseTeamStats(team.Stats)
+		if err != nil {
+			return nil, err
 		}
 		teamstats[team.TeamId] = statline
 	}
 	return &teamstats, nil
 }
 
-func (yc *YahooClient) MyStats() (*StatLine, error) {
-	leaguestats, err := yc.CurrentStats()
+// Fetches the matchups for the given week, or for the current week if week
+// is 0.
+func (yc *YahooClient) GetScoreboard(ctx context.Context, leagueKey string, week int) (*YahooScoreboard, error) {
+	url := fmt.Sprintf("%s/league/%s/scoreboard", yc.apiUrl, leagueKey)
+	if week > 0 {
+		url = fmt.Sprintf("%s;week=%d", url, week)
+	}
+
+	body, err := yc.Get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var data FantasyContent
+	err = xml.Unmarshal([]byte(body), &data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.League.Scoreboard, nil
+}
+
+func (yc *YahooClient) MyStats(ctx context.Context, league *LeagueContext) (*StatLine, error) {
+	leaguestats, err := yc.CurrentStats(ctx, league.LeagueKey)
 	if err != nil {
 		return nil, err
 	}
-	mystats := (*leaguestats)[6]
+	mystats, ok := (*leaguestats)[league.MyTeamID]
+	if !ok {
+		return nil, fmt.Errorf("No stats for team %s", league.MyTeamKey)
+	}
 	return &mystats, nil
 }
 
-func (yc *YahooClient) LeagueRosters() (*map[TeamID][]YahooPlayer, error) {
-	response, err := yc.cacheGet(
-		"league_rosters",
-		"http://fantasysports.yahooapis.com/fantasy/v2/league/308.l.21006/teams/roster")
-//		"http://fantasysports.yahooapis.com/fantasy/v2/league/mlb.l.5181/teams/roster")
+func (yc *YahooClient) LeagueRosters(ctx context.Context, leagueKey string) (*map[TeamID][]YahooPlayer, error) {
+	response, err := yc.cacheGet(ctx,
+		"league_rosters_"+leagueKey,
+		fmt.Sprintf("%s/league/%s/teams/roster", yc.apiUrl, leagueKey))
 
 	if err != nil {
 		return nil, err
 	}
 
-	//	fmt.Println(response)
-
 	var data FantasyContent
 	err = xml.Unmarshal([]byte(response), &data)
 	if err != nil {
@@ -276,18 +551,15 @@ func (yc *YahooClient) LeagueRosters() (*map[TeamID][]YahooPlayer, error) {
 	return &rosters, nil
 }
 
-func (yc *YahooClient) MyRoster() (*[]YahooPlayer, error) {
-	response, err := yc.cacheGet(
-		"my_roster",
-		"http://fantasysports.yahooapis.com/fantasy/v2/team/308.l.21006.t.6/roster")
-//		"http://fantasysports.yahooapis.com/fantasy/v2/team/mlb.l.5181.t.6/roster")
+func (yc *YahooClient) MyRoster(ctx context.Context, teamKey string) (*[]YahooPlayer, error) {
+	response, err := yc.cacheGet(ctx,
+		"my_roster_"+teamKey,
+		fmt.Sprintf("%s/team/%s/roster", yc.apiUrl, teamKey))
 
 	if err != nil {
 		return nil, err
 	}
 
-	//	fmt.Println(response)
-
 	var data FantasyContent
 	err = xml.Unmarshal([]byte(response), &data)
 	if err != nil {
@@ -297,14 +569,121 @@ func (yc *YahooClient) MyRoster() (*[]YahooPlayer, error) {
 	return &data.Team.Roster, nil
 }
 
+// Yahoo won't return more than this many players per request.
+const YAHOO_PLAYERS_PER_PAGE = 25
+
+// Fetches the top free agents (by Yahoo's overall rank) at a position, or at
+// every position if position is empty.
+func (yc *YahooClient) GetFreeAgents(ctx context.Context, leagueKey, position string, count int) ([]YahooPlayer, error) {
+	return fetchPages(count, YAHOO_PLAYERS_PER_PAGE, func(start, n int) ([]YahooPlayer, error) {
+		url := fmt.Sprintf("%s/league/%s/players;status=FA;sort=AR;start=%d;count=%d", yc.apiUrl, leagueKey, start, n)
+		if position != "" {
+			url += ";position=" + position
+		}
+
+		body, err := yc.Get(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+
+		var data FantasyContent
+		err = xml.Unmarshal([]byte(body), &data)
+		if err != nil {
+			return nil, err
+		}
+		return data.League.Players, nil
+	})
+}
+
+// Fetches up to count players, pageSize at a time, stopping early if a page
+// comes back short.
+func fetchPages(count, pageSize int, fetch func(start, n int) ([]YahooPlayer, error)) ([]YahooPlayer, error) {
+	players := []YahooPlayer{}
+	for len(players) < count {
+		n := min(pageSize, count-len(players))
+		page, err := fetch(len(players), n)
+		if err != nil {
+			return nil, err
+		}
+		players = append(players, page...)
+		if len(page) < n {
+			break
+		}
+	}
+	if len(players) > count {
+		players = players[:count]
+	}
+	return players, nil
+}
+
+// Figures out which league (and which team in that league) to analyze.
+//
+// Either key may be left empty, in which case we look it up: the game
+// defaults to the current MLB season, and the league defaults to the only
+// league the logged-in user is in for that game.  The user's team is always
+// the one that is owned by the current login.
+func (yc *YahooClient) DiscoverLeagueContext(ctx context.Context, gameKey, leagueKey string) (*LeagueContext, error) {
+	if len(leagueKey) > 0 {
+		gameKey = gameKeyFromLeagueKey(leagueKey)
+	}
+
+	if len(gameKey) == 0 {
+		games, err := yc.GetGames(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(games) != 1 {
+			return nil, fmt.Errorf("Wrong number of matching games: %d", len(games))
+		}
+		gameKey = games[0].GameKey
+	}
+
+	if len(leagueKey) == 0 {
+		leagues, err := yc.GetLeagues(ctx, gameKey)
+		if err != nil {
+			return nil, err
+		}
+		if len(leagues) != 1 {
+			names := []string{}
+			for _, league := range leagues {
+				names = append(names, fmt.Sprintf("%s (%s)", league.LeagueKey, league.Name))
+			}
+			return nil, fmt.Errorf("Found %d leagues for game %s, please pick one of: %s",
+				len(leagues), gameKey, strings.Join(names, ", "))
+		}
+		leagueKey = leagues[0].LeagueKey
+	}
+
+	teams, err := yc.GetTeams(ctx, leagueKey)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, team := range teams {
+		if team.IsMyTeam == 1 {
+			return &LeagueContext{
+				GameKey:   gameKey,
+				LeagueKey: leagueKey,
+				MyTeamKey: team.TeamKey,
+				MyTeamID:  team.TeamId,
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("You don't own a team in league %s", leagueKey)
+}
+
 //
 // Structures
 //
 
 type YahooClient struct {
-	tokenFile string
-	oauth     *oauth.Consumer
-	cache     ReadThroughCache
+	tokens  TokenProvider
+	client  *http.Client
+	cache   ReadThroughCache
+	apiUrl  string // Overridden in tests
+	limiter *TokenBucket
+	retry   RetryPolicy
 }
 
 type FantasyContent struct {
@@ -353,6 +732,11 @@ type YahooPlayer struct {
 	FullName     string   `xml:"name>full"`
 	PositionType string   `xml:"position_type"`
 	Position     []string `xml:"eligible_positions>position"`
+	TeamAbbr     string   `xml:"editorial_team_abbr"`
+	TeamName     string   `xml:"editorial_team_full_name"`
+	// Where the player is in the lineup (e.g. "SS", or "BN"), for rosters
+	// fetched for a particular date.
+	SelectedPosition string `xml:"selected_position>position"`
 	StartingStatus []YahooStartingStatus `xml:"starting_status"`
 }
 
@@ -362,160 +746,280 @@ type YahooLeague struct {
 	LeagueKey string      `xml:"league_key"`
 	Id        int         `xml:"league_id"`
 	Name      string      `xml:"name"`
+
+	// One of "roto", "head" (head-to-head categories), "point" or
+	// "headpoint" (head-to-head points).
+	ScoringType string `xml:"scoring_type"`
+
+	// e.g. "2014-03-30"
+	StartDate string `xml:"start_date"`
+	EndDate   string `xml:"end_date"`
+
+	Settings   YahooLeagueSettings `xml:"settings"`
+	Scoreboard YahooScoreboard     `xml:"scoreboard"`
+	Players    []YahooPlayer       `xml:"players>player"`
+}
+
+type YahooScoreboard struct {
+	Week     int            `xml:"week"`
+	Matchups []YahooMatchup `xml:"matchups>matchup"`
+}
+
+type YahooMatchup struct {
+	Week      int         `xml:"week"`
+	WeekStart string      `xml:"week_start"`
+	WeekEnd   string      `xml:"week_end"`
+	Status    string      `xml:"status"`
+	Teams     []YahooTeam `xml:"teams>team"`
+}
+
+type YahooLeagueSettings struct {
+	StatCategories  []YahooStatCategory   `xml:"stat_categories>stats>stat"`
+	StatModifiers   []YahooStat           `xml:"stat_modifiers>stats>stat"`
+	RosterPositions []YahooRosterPosition `xml:"roster_positions>roster_position"`
+	// Weekly caps for head-to-head leagues, if the league has them.
+	MaxInningsPitched string `xml:"max_innings_pitched"`
+	MaxGamesStarted   string `xml:"max_games_started"`
+}
+
+type YahooStatCategory struct {
+	ID                int    `xml:"stat_id"`
+	Enabled           string `xml:"enabled"`
+	DisplayName       string `xml:"display_name"`
+	SortOrder         string `xml:"sort_order"`
+	PositionType      string `xml:"position_type"`
+	IsOnlyDisplayStat int    `xml:"is_only_display_stat"`
+}
+
+type YahooRosterPosition struct {
+	Position     string `xml:"position"`
+	PositionType string `xml:"position_type"`
+	Count        int    `xml:"count"`
+}
+
+// Identifies the league we're analyzing, and which team in it is ours.
+type LeagueContext struct {
+	GameKey   string // e.g. "328"
+	LeagueKey string // e.g. "328.l.1305"
+	MyTeamKey string // e.g. "328.l.1305.t.5"
+	MyTeamID  TeamID // e.g. 5
+}
+
+// The parts of a league's configuration which affect how we evaluate it.
+type LeagueSettings struct {
+	Categories   ScoringCategories
+	PointWeights PointWeights // Only set for points leagues
+	Topology     RosterTopology
+	Season       SeasonCalendar
+
+	// Weekly caps on my pitchers, or zero if there are none.
+	MaxWeeklyStarts  int
+	MaxWeeklyInnings float64
+}
+
+// How to compare teams (or players) in this league.
+func (s *LeagueSettings) Scorer() Scorer {
+	if len(s.PointWeights) > 0 {
+		return s.PointWeights
+	}
+	return s.Categories
 }
 
 //
 // Implementation
 //
 
-func (yc *YahooClient) Try(key, url string) (string, error){
-	return yc.cacheGet(key, url)
+func (yc *YahooClient) Try(ctx context.Context, key, url string) (string, error){
+	return yc.cacheGet(ctx, key, url)
 }
 
-func oauthUrlFetcher(yc *YahooClient, url string) FetchFunction {
+func oauthUrlFetcher(ctx context.Context, yc *YahooClient, url string) FetchFunction {
 	return func() (string, error) {
 		log.Printf("Fetching (via OAuth): '%s'", url)
-		return yc.Get(url)
-	}
-}
-
-func (yc *YahooClient) cacheGet(key string, url string) (string, error) {
-	return yc.cache.Get(oauthUrlFetcher(yc, url), key, time.Hour*24)
-}
-
-
-// yurl http://fantasysports.yahooapis.com/fantasy/v2/game/328/stat_categories
-func mapYahooIdToStatId() map[int]StatID {
-	return map[int]StatID{
-		1:  B_GAMES,
-		6:  B_AT_BATS,
-		7:  B_RUNS,
-		12: B_HOME_RUNS,
-		13: B_RUNS_BATTED_IN,
-		16: B_STOLEN_BASES,
-		3:  B_BATTING_AVG,
-		65: B_PLATE_APPS,
-		4: B_ON_BASE_PCT,
-		5: B_SLUGGING,
-		9: B_SINGLES,
-		10: B_DOUBLES,
-		11: B_TRIPLES,
-		17: B_CAUGHT_STEALING,
-		18: B_WALKS,
-		21: B_STRIKE_OUTS,
-
-		50: P_INNINGS,
-		28: P_WINS,
-		32: P_SAVES,
-		42: P_STRIKE_OUTS,
-		26: P_EARNED_RUN_AVERAGE,
-		27: P_WHIP,
-		34: P_HITS,
-		35: P_BATTERS_FACED,
-		36: P_RUNS,
-		37: P_EARNED_RUNS,
-		38: P_HOME_RUNS,
-		39: P_WALKS,
-		47: P_SAVE_CHANCES,
-	}
-}
-
-
-func (yc *YahooClient) getAccessToken() (*oauth.AccessToken, error) {
-	savedBytes, err := ioutil.ReadFile(yc.tokenFile)
-	savedString := string(savedBytes)
-	var accessToken *oauth.AccessToken
-	if err == nil && len(savedString) > 0 {
-		accessToken, err = accessTokenFromPlainString(savedString)
-
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		requestToken, url, err := yc.oauth.GetRequestTokenAndUrl("oob")
+		return yc.Get(ctx, url)
+	}
+}
 
-		fmt.Println("(1) Go to: " + url)
-		fmt.Println("(2) Grant access, you should get back a verification code.")
-		fmt.Println("(3) Enter that verification code here: ")
+func (yc *YahooClient) cacheGet(ctx context.Context, key string, url string) (string, error) {
+	return yc.cache.Get(oauthUrlFetcher(ctx, yc, url), key, time.Hour*24)
+}
 
-		verificationCode := ""
-		fmt.Scanln(&verificationCode)
+// Converts a team's stats from Yahoo's format.  Stats which haven't accrued
+// yet (e.g. ERA before any innings are pitched) are reported as "-", and are
+// left out.
+func parseTeamStats(stats []YahooStat) (StatLine, error) {
+	statline := make(StatLine)
+	for _, stat := range stats {
+		if stat.Value == "" || stat.Value == "-" {
+			continue
+		}
 
-		accessToken, err = yc.oauth.AuthorizeToken(requestToken, verificationCode)
+		// H/AB is display-only, but it's the only place Yahoo gives us the
+		// components of batting average.
+		if stat.ID == YAHOO_HITS_PER_AT_BAT {
+			parts := strings.Split(stat.Value, "/")
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("Malformed H/AB: '%s'", stat.Value)
+			}
+			hits, err := strconv.ParseFloat(parts[0], 64)
+			if err != nil {
+				return nil, err
+			}
+			atBats, err := strconv.ParseFloat(parts[1], 64)
+			if err != nil {
+				return nil, err
+			}
+			statline[B_HITS] = Stat(hits)
+			statline[B_AT_BATS] = Stat(atBats)
+			continue
+		}
 
+		statid, ok := statIdByYahooId[stat.ID]
+		if !ok {
+			continue
+		}
+		statval, err := strconv.ParseFloat(stat.Value, 64)
 		if err != nil {
 			return nil, err
 		}
-		ioutil.WriteFile(yc.tokenFile, []byte(toPlainString(*accessToken)), 0644)
+		if statid == P_INNINGS {
+			statval = fromBaseballInnings(statval)
+		}
+		statline[statid] = Stat(statval)
 	}
+	return statline, nil
+}
 
-	return accessToken, nil
+// Yahoo reports innings the way box scores do, where "6.1" means six and one
+// third innings.
+func fromBaseballInnings(ip float64) float64 {
+	whole := math.Floor(ip)
+	outs := math.Round((ip - whole) * 10)
+	return whole + outs/3
 }
 
-func toPlainString(t oauth.AccessToken) string {
-	return fmt.Sprintf("%d|%s|%d|%s", len(t.Token), t.Token, len(t.Secret), t.Secret)
+// League keys look like "<game key>.l.<league id>".
+func gameKeyFromLeagueKey(leagueKey string) string {
+	return strings.SplitN(leagueKey, ".", 2)[0]
 }
 
-func accessTokenFromPlainString(s string) (*oauth.AccessToken, error) {
-	firstBar := strings.Index(s, "|")
-	if firstBar == -1 {
-		return nil, fmt.Errorf("Malformed input [%s]. Couldn't find first bar.", s)
+func parseLeagueSettings(league YahooLeague) (*LeagueSettings, error) {
+	settings := league.Settings
+	categories := ScoringCategories{}
+	for _, category := range settings.StatCategories {
+		// Display-only stats (e.g. H/AB) show up in the list, but don't score.
+		if category.Enabled == "0" || category.IsOnlyDisplayStat == 1 {
+			continue
+		}
+		statid, ok := statIdByYahooId[category.ID]
+		if !ok {
+			log.Printf("Ignoring unknown scoring category: %d (%s)", category.ID, category.DisplayName)
+			continue
+		}
+		// Yahoo uses sort_order 1 for "higher is better" and 0 for "lower is
+		// better".  Without one, we go by the stat.
+		switch category.SortOrder {
+		case "0":
+			categories[statid] = LOWER_IS_BETTER
+		case "1":
+			categories[statid] = HIGHER_IS_BETTER
+		default:
+			categories[statid] = defaultSortOrder(statid)
+		}
 	}
 
-	len1, err := strconv.Atoi(s[0:firstBar])
-	if err != nil {
-		return nil, fmt.Errorf("Malformed input [%s]", s)
+	if len(categories) == 0 {
+		return nil, fmt.Errorf("League has no scoring categories we understand")
 	}
-	token := s[firstBar+1 : firstBar+1+len1]
 
-	secondBar := firstBar + len1 + 1
-	if s[secondBar] != '|' {
-		return nil, fmt.Errorf("Malformed input [%s]. Char %d is not '|'", s, secondBar)
+	var weights PointWeights
+	if strings.Contains(league.ScoringType, "point") {
+		weights = PointWeights{}
+		for _, modifier := range settings.StatModifiers {
+			statid, ok := statIdByYahooId[modifier.ID]
+			if !ok {
+				log.Printf("Ignoring points for unknown stat: %d", modifier.ID)
+				continue
+			}
+			value, err := strconv.ParseFloat(modifier.Value, 32)
+			if err != nil {
+				return nil, err
+			}
+			weights[statid] = float32(value)
+		}
+		if len(weights) == 0 {
+			return nil, fmt.Errorf("Points league has no stat modifiers we understand")
+		}
 	}
 
-	secondHalf := s[secondBar+1:]
-
-	thirdBar := strings.Index(secondHalf, "|")
-	if thirdBar == -1 {
-		return nil, fmt.Errorf("Malformed input [%s]. Couldn't find third bar.", s)
+	var season SeasonCalendar
+	if league.StartDate != "" && league.EndDate != "" {
+		var err error
+		season, err = NewSeasonCalendar(league.StartDate, league.EndDate)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	len2, err := strconv.Atoi(secondHalf[0:thirdBar])
-	if err != nil {
-		return nil, fmt.Errorf("Malformed input [%s]", s)
+	var maxStarts int
+	var maxInnings float64
+	if settings.MaxGamesStarted != "" {
+		var err error
+		maxStarts, err = strconv.Atoi(settings.MaxGamesStarted)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if settings.MaxInningsPitched != "" {
+		var err error
+		maxInnings, err = strconv.ParseFloat(settings.MaxInningsPitched, 64)
+		if err != nil {
+			return nil, err
+		}
 	}
-	secret := secondHalf[thirdBar+1 : thirdBar+1+len2]
 
-	return &oauth.AccessToken{Token: token, Secret: secret}, nil
+	return &LeagueSettings{
+		Categories:       categories,
+		PointWeights:     weights,
+		Topology:         NewRosterTopology(settings.RosterPositions),
+		Season:           season,
+		MaxWeeklyStarts:  maxStarts,
+		MaxWeeklyInnings: maxInnings,
+	}, nil
 }
 
-// yurl http://fantasysports.yahooapis.com/fantasy/v2/game/mlb
+// Yahoo stat ids which don't correspond to a single one of our stats.
+const (
+	YAHOO_HITS_PER_AT_BAT = 60
+)
+
+// yurl https://fantasysports.yahooapis.com/fantasy/v2/game/mlb
 //   <game_key>328</game_key>
 //
-// yurl http://fantasysports.yahooapis.com/fantasy/v2/users;use_login=1/games;game_keys=328/leagues
+// yurl https://fantasysports.yahooapis.com/fantasy/v2/users;use_login=1/games;game_keys=328/leagues
 //   <league_key>328.l.1305</league_key>
 //   <league_id>1305</league_id>
 //   <name>Princeton Sucks</name>
 //
-// yurl http://fantasysports.yahooapis.com/fantasy/v2/leagues;league_keys=328.l.1305
+// yurl https://fantasysports.yahooapis.com/fantasy/v2/leagues;league_keys=328.l.1305
 // ...
 //
-// yurl http://fantasysports.yahooapis.com/fantasy/v2/leagues;league_keys=328.l.1305/teams
+// yurl https://fantasysports.yahooapis.com/fantasy/v2/leagues;league_keys=328.l.1305/teams
 //    <team>
 //      <team_key>328.l.1305.t.5</team_key>
 //      <team_id>5</team_id>
 //      <name>Curse of Andino</name>
 //      <is_owned_by_current_login>1</is_owned_by_current_login>
 //
-// yurl http://fantasysports.yahooapis.com/fantasy/v2/team/328.l.1305.t.5/roster
+// yurl https://fantasysports.yahooapis.com/fantasy/v2/team/328.l.1305.t.5/roster
 //   <player>
 //     <player_key>328.p.8395</player_key>
 //     <player_id>8395</player_id>
 //     <name>
 //       <full>Matt Wieters</full>
 //
-// yurl http://fantasysports.yahooapis.com/fantasy/v2/player/328.p.8395/metadata
-// yurl http://fantasysports.yahooapis.com/fantasy/v2/player/328.p.8395/stats
+// yurl https://fantasysports.yahooapis.com/fantasy/v2/player/328.p.8395/metadata
+// yurl https://fantasysports.yahooapis.com/fantasy/v2/player/328.p.8395/stats
 // 
 
 
@@ -0,0 +1,315 @@
+package folib
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const settingsXml = `<?xml version="1.0" encoding="UTF-8"?>
+<fantasy_content>
+  <league>
+    <league_key>328.l.1305</league_key>
+    <start_date>2014-03-30</start_date>
+    <end_date>2014-09-28</end_date>
+    <settings>
+      <roster_positions>
+        <roster_position><position>C</position><position_type>B</position_type><count>1</count></roster_position>
+        <roster_position><position>OF</position><position_type>B</position_type><count>5</count></roster_position>
+        <roster_position><position>SP</position><position_type>P</position_type><count>6</count></roster_position>
+        <roster_position><position>BN</position><count>4</count></roster_position>
+      </roster_positions>
+      <max_innings_pitched>60</max_innings_pitched>
+      <max_games_started>12</max_games_started>
+      <stat_categories>
+        <stats>
+          <stat><stat_id>60</stat_id><enabled>1</enabled><display_name>H/AB</display_name><sort_order>1</sort_order><position_type>B</position_type><is_only_display_stat>1</is_only_display_stat></stat>
+          <stat><stat_id>7</stat_id><enabled>1</enabled><display_name>R</display_name><sort_order>1</sort_order><position_type>B</position_type></stat>
+          <stat><stat_id>4</stat_id><enabled>1</enabled><display_name>OBP</display_name><sort_order>1</sort_order><position_type>B</position_type></stat>
+          <stat><stat_id>83</stat_id><enabled>1</enabled><display_name>QS</display_name><sort_order>1</sort_order><position_type>P</position_type></stat>
+          <stat><stat_id>57</stat_id><enabled>1</enabled><display_name>K/9</display_name><sort_order>1</sort_order><position_type>P</position_type></stat>
+          <stat><stat_id>26</stat_id><enabled>1</enabled><display_name>ERA</display_name><sort_order>0</sort_order><position_type>P</position_type></stat>
+        </stats>
+      </stat_categories>
+    </settings>
+  </league>
+</fantasy_content>`
+
+func TestParseLeagueSettings(t *testing.T) {
+	var data FantasyContent
+	if err := xml.Unmarshal([]byte(settingsXml), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	settings, err := parseLeagueSettings(data.League)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ScoringCategories{
+		B_RUNS:               HIGHER_IS_BETTER,
+		B_ON_BASE_PCT:        HIGHER_IS_BETTER,
+		P_QUALITY_STARTS:     HIGHER_IS_BETTER,
+		P_STRIKEOUTS_PER_9:   HIGHER_IS_BETTER,
+		P_EARNED_RUN_AVERAGE: LOWER_IS_BETTER,
+	}
+	if len(settings.Categories) != len(expected) {
+		t.Errorf("Expected %d categories, got: %v", len(expected), settings.Categories)
+	}
+	for stat, order := range expected {
+		actual, ok := settings.Categories[stat]
+		if !ok || actual != order {
+			t.Errorf("Stat %d: expected order %d, got %d (present: %t)", stat, order, actual, ok)
+		}
+	}
+
+	topology := settings.Topology
+	if topology.Starters["C"] != 1 || topology.Starters["OF"] != 5 || topology.Starters["SP"] != 6 {
+		t.Errorf("Wrong starting slots: %v", topology.Starters)
+	}
+	if topology.Bench != 4 {
+		t.Errorf("Expected 4 bench slots, got: %d", topology.Bench)
+	}
+	if topology.StartingSlots() != 12 {
+		t.Errorf("Expected 12 starting slots, got: %d", topology.StartingSlots())
+	}
+
+	if settings.Season.Days() != 183 {
+		t.Errorf("Expected a 183 day season, got: %v", settings.Season)
+	}
+	if settings.MaxWeeklyStarts != 12 || settings.MaxWeeklyInnings != 60 {
+		t.Errorf("Expected caps of 12 starts and 60 innings, got: %d and %f",
+			settings.MaxWeeklyStarts, settings.MaxWeeklyInnings)
+	}
+
+	if _, ok := settings.Scorer().(ScoringCategories); !ok {
+		t.Errorf("Expected a roto league, got: %v", settings.Scorer())
+	}
+}
+
+const pointsSettingsXml = `<?xml version="1.0" encoding="UTF-8"?>
+<fantasy_content>
+  <league>
+    <league_key>328.l.999</league_key>
+    <scoring_type>point</scoring_type>
+    <settings>
+      <stat_categories>
+        <stats>
+          <stat><stat_id>12</stat_id><enabled>1</enabled><display_name>HR</display_name><sort_order>1</sort_order><position_type>B</position_type></stat>
+          <stat><stat_id>16</stat_id><enabled>1</enabled><display_name>SB</display_name><sort_order>1</sort_order><position_type>B</position_type></stat>
+          <stat><stat_id>50</stat_id><enabled>1</enabled><display_name>IP</display_name><sort_order>1</sort_order><position_type>P</position_type></stat>
+          <stat><stat_id>37</stat_id><enabled>1</enabled><display_name>ER</display_name><position_type>P</position_type></stat>
+        </stats>
+      </stat_categories>
+      <stat_modifiers>
+        <stats>
+          <stat><stat_id>12</stat_id><value>4</value></stat>
+          <stat><stat_id>16</stat_id><value>2</value></stat>
+          <stat><stat_id>50</stat_id><value>3</value></stat>
+          <stat><stat_id>37</stat_id><value>-2</value></stat>
+        </stats>
+      </stat_modifiers>
+    </settings>
+  </league>
+</fantasy_content>`
+
+func TestParsePointsLeagueSettings(t *testing.T) {
+	var data FantasyContent
+	if err := xml.Unmarshal([]byte(pointsSettingsXml), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	settings, err := parseLeagueSettings(data.League)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	weights, ok := settings.Scorer().(PointWeights)
+	if !ok {
+		t.Fatalf("Expected a points league, got: %v", settings.Scorer())
+	}
+
+	expected := PointWeights{B_HOME_RUNS: 4, B_STOLEN_BASES: 2, P_INNINGS: 3, P_EARNED_RUNS: -2}
+	if len(weights) != len(expected) {
+		t.Errorf("Expected %d weights, got: %v", len(expected), weights)
+	}
+	for stat, weight := range expected {
+		if weights[stat] != weight {
+			t.Errorf("Stat %d: expected %f points, got %f", stat, weight, weights[stat])
+		}
+	}
+	// ER has no sort_order, so goes by the stat.
+	if settings.Categories[P_EARNED_RUNS] != LOWER_IS_BETTER || settings.Categories[B_HOME_RUNS] != HIGHER_IS_BETTER {
+		t.Errorf("Wrong sort orders: %v", settings.Categories)
+	}
+}
+
+const gamesXml = `<?xml version="1.0"?><fantasy_content><game><game_key>328</game_key></game></fantasy_content>`
+
+func (f *fakeYahoo) requestCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.requests)
+}
+
+func TestGetRetriesTemporaryErrors(t *testing.T) {
+	yahoo, client := newFakeYahoo(t)
+	yahoo.respond("/game/mlb", gamesXml)
+	yahoo.queue("GET", "/game/mlb", YAHOO_THROTTLED, "<html>Request denied</html>")
+	yahoo.queue("GET", "/game/mlb", http.StatusServiceUnavailable, "")
+
+	games, err := client.GetGames(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 1 || yahoo.requestCount() != 3 {
+		t.Errorf("Expected to succeed on the third try, got %v after %d requests", games, yahoo.requestCount())
+	}
+
+	// Until we run out of attempts.
+	for i := 0; i < 5; i++ {
+		yahoo.queue("GET", "/game/mlb", http.StatusBadGateway, "")
+	}
+	_, err = client.GetGames(context.Background())
+	yahooErr, ok := err.(*YahooError)
+	if !ok || !yahooErr.Temporary() {
+		t.Errorf("Expected a temporary YahooError, got: %v", err)
+	}
+	if yahoo.requestCount() != 3+client.retry.MaxAttempts {
+		t.Errorf("Expected %d attempts, got %d", client.retry.MaxAttempts, yahoo.requestCount()-3)
+	}
+}
+
+func TestGetReturnsYahooError(t *testing.T) {
+	yahoo, client := newFakeYahoo(t)
+	yahoo.respondWithStatus("GET", "/league/328.l.1/settings", http.StatusBadRequest,
+		`<?xml version="1.0" encoding="UTF-8"?>
+<error xml:lang="en-us" xmlns="http://www.yahooapis.com/v1/base.rng">
+ <description>League key 328.l.1 does not exist.</description>
+ <detail/>
+</error>`)
+
+	_, err := client.GetLeagueSettings(context.Background(), "328.l.1")
+	yahooErr, ok := err.(*YahooError)
+	if !ok {
+		t.Fatalf("Expected a YahooError, got: %v", err)
+	}
+	if yahooErr.StatusCode != http.StatusBadRequest || yahooErr.Description != "League key 328.l.1 does not exist." {
+		t.Errorf("Wrong error: %v", yahooErr)
+	}
+	if yahooErr.Temporary() || yahoo.requestCount() != 1 {
+		t.Errorf("Expected a bad request not to be retried, got %d requests", yahoo.requestCount())
+	}
+}
+
+func TestGetCancelled(t *testing.T) {
+	yahoo, client := newFakeYahoo(t)
+	yahoo.respond("/game/mlb", gamesXml)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.GetGames(ctx); err != context.Canceled {
+		t.Errorf("Expected the request to be cancelled, got: %v", err)
+	}
+	if yahoo.requestCount() != 0 {
+		t.Errorf("Expected no requests, got %d", yahoo.requestCount())
+	}
+
+	// Backing off gives up when the context does.
+	client.retry = RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour}
+	yahoo.queue("GET", "/game/mlb", http.StatusServiceUnavailable, "")
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.GetGames(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected the deadline to pass, got: %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Expected to stop backing off at the deadline, took %v", time.Since(start))
+	}
+}
+
+func statsXml(playerKeys []string) string {
+	players := ""
+	for i, key := range playerKeys {
+		players += fmt.Sprintf(`<player><player_key>%s</player_key><player_stats><stats>
+<stat><stat_id>7</stat_id><value>%d</value></stat>
+<stat><stat_id>60</stat_id><value>3/4</value></stat>
+<stat><stat_id>50</stat_id><value>6.1</value></stat>
+</stats></player_stats></player>`, key, i)
+	}
+	return `<?xml version="1.0"?><fantasy_content><players>` + players + `</players></fantasy_content>`
+}
+
+func TestGetStats(t *testing.T) {
+	yahoo, client := newFakeYahoo(t)
+
+	keys := []string{}
+	for i := 0; i < 2*STATS_PLAYERS_PER_REQUEST+5; i++ {
+		keys = append(keys, fmt.Sprintf("328.p.%d", i))
+	}
+	batches := [][]string{
+		keys[:STATS_PLAYERS_PER_REQUEST],
+		keys[STATS_PLAYERS_PER_REQUEST : 2*STATS_PLAYERS_PER_REQUEST],
+		keys[2*STATS_PLAYERS_PER_REQUEST:],
+	}
+	path := func(batch []string) string {
+		return "/players;player_keys=" + strings.Join(batch, ",") + "/stats;type=week;week=3"
+	}
+	yahoo.respond(path(batches[0]), statsXml(batches[0]))
+	yahoo.respond(path(batches[2]), statsXml(batches[2]))
+	yahoo.respondWithStatus("GET", path(batches[1]), http.StatusBadRequest,
+		`<?xml version="1.0"?><error><description>Invalid player key</description></error>`)
+
+	stats, err := client.GetStats(context.Background(), keys, StatsScope{Week: 3})
+
+	// The batches that worked still come back.
+	if len(stats) != len(batches[0])+len(batches[2]) {
+		t.Errorf("Expected stats for %d players, got %d", len(batches[0])+len(batches[2]), len(stats))
+	}
+	if line := stats["328.p.41"]; line[B_RUNS] != 1 {
+		t.Errorf("Expected 1 run for 328.p.41, got: %v", line)
+	}
+	if line := stats["328.p.41"]; !closeEnough(line[P_INNINGS], 6.333) {
+		t.Errorf("Expected 6 1/3 innings for 328.p.41, got: %v", line)
+	}
+
+	statsErr, ok := err.(*StatsError)
+	if !ok {
+		t.Fatalf("Expected a StatsError, got: %v", err)
+	}
+	if statsErr.Batches != 3 || len(statsErr.Failures) != 1 ||
+		strings.Join(statsErr.Failures[0].PlayerKeys, ",") != strings.Join(batches[1], ",") {
+		t.Errorf("Expected the second batch to fail, got: %v", statsErr)
+	}
+	var yahooErr *YahooError
+	if !errors.As(err, &yahooErr) || yahooErr.Description != "Invalid player key" {
+		t.Errorf("Expected Yahoo's error to be wrapped, got: %v", err)
+	}
+}
+
+func TestStatsScope(t *testing.T) {
+	examples := []struct {
+		scope StatsScope
+		path  string
+		err   bool
+	}{
+		{StatsScope{}, "", false},
+		{StatsScope{Week: 3}, ";type=week;week=3", false},
+		{StatsScope{Date: "2014-05-01"}, ";type=date;date=2014-05-01", false},
+		{StatsScope{Week: 3, Date: "2014-05-01"}, "", true},
+		{StatsScope{Week: -1}, "", true},
+		{StatsScope{Date: "May 1"}, "", true},
+	}
+	for _, example := range examples {
+		path, err := example.scope.path()
+		if path != example.path || (err != nil) != example.err {
+			t.Errorf("%v: expected %q (error %t), got %q, %v", example.scope, example.path, example.err, path, err)
+		}
+	}
+}
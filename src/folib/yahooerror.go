@@ -0,0 +1,58 @@
+package folib
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// The status Yahoo sends when it's throttling us.
+const YAHOO_THROTTLED = 999
+
+// A request Yahoo turned down, with the reason it gave, e.g.
+//
+//	<error><description>Player is not available</description></error>
+type YahooError struct {
+	Method      string
+	Url         string
+	StatusCode  int
+	Status      string
+	Description string
+}
+
+func (e *YahooError) Error() string {
+	return fmt.Sprintf("%s %s failed (%s): %s", e.Method, e.Url, e.Status, e.Description)
+}
+
+// Whether the same request might succeed later, i.e. Yahoo is throttling us
+// or having trouble of its own.
+func (e *YahooError) Temporary() bool {
+	return e.StatusCode == YAHOO_THROTTLED || e.StatusCode >= 500
+}
+
+// Builds the error for a response Yahoo didn't accept.  Yahoo usually
+// explains itself in XML, but throttling and proxy errors come back as HTML
+// or nothing at all, so the body is kept as is if it isn't Yahoo's XML.
+func parseYahooError(method, url string, response *http.Response, body []byte) *YahooError {
+	e := &YahooError{
+		Method:     method,
+		Url:        url,
+		StatusCode: response.StatusCode,
+		Status:     response.Status,
+	}
+	if e.Status == "" {
+		e.Status = fmt.Sprint(response.StatusCode)
+	}
+
+	var data struct {
+		XMLName     xml.Name `xml:"error"`
+		Description string   `xml:"description"`
+	}
+	if err := xml.Unmarshal(body, &data); err == nil && data.Description != "" {
+		e.Description = strings.TrimSpace(data.Description)
+	} else {
+		e.Description = strings.TrimSpace(string(body))
+	}
+	return e
+}
@@ -0,0 +1,33 @@
+package folib
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestParseYahooError(t *testing.T) {
+	examples := []struct {
+		status      int
+		body        string
+		description string
+		temporary   bool
+	}{
+		{400, `<?xml version="1.0"?><error><description>Player is not available</description><detail/></error>`, "Player is not available", false},
+		{YAHOO_THROTTLED, "<html><body>Request denied</body></html>", "<html><body>Request denied</body></html>", true},
+		{503, "", "", true},
+		{401, `<error><description>
+  Please provide valid credentials.
+</description></error>`, "Please provide valid credentials.", false},
+	}
+	for _, example := range examples {
+		response := &http.Response{StatusCode: example.status}
+		e := parseYahooError("GET", "http://yahoo/game/mlb", response, []byte(example.body))
+		if e.Description != example.description || e.Temporary() != example.temporary {
+			t.Errorf("%d %q: expected %q (temporary %t), got %q (temporary %t)",
+				example.status, example.body, example.description, example.temporary, e.Description, e.Temporary())
+		}
+		if e.Status == "" {
+			t.Errorf("%d: expected a status", example.status)
+		}
+	}
+}
@@ -19,6 +19,11 @@ const (
 type ZipsClient struct {
 	battingStats  *map[PlayerID]StatLine
 	pitchingStats *map[PlayerID]StatLine
+	players       []PlayerRecord
+}
+
+func (zc *ZipsClient) Players() []PlayerRecord {
+	return zc.players
 }
 
 func (zc *ZipsClient) GetStat(player PlayerID, stat StatID) Stat {
@@ -35,55 +40,22 @@ func (zc *ZipsClient) GetStatLine(player PlayerID) StatLine {
 }
 
 func NewZipsClient() (*ZipsClient, error) {
-	battingStats, err := indexBattingStats()
+	battingRows, err := indexBattingStats()
 	if err != nil {
 		return nil, err
 	}
 
-	pitchingStats, err := indexPitchingStats()
+	pitchingRows, err := indexPitchingStats()
 	if err != nil {
 		return nil, err
 	}
 
-	return &ZipsClient{battingStats: battingStats, pitchingStats: pitchingStats}, nil
-}
-
-func mapColumnNameToBattingStat() map[ColName]StatID {
-	return map[ColName]StatID{
-		"AB":  B_AT_BATS,
-		"BA":  B_BATTING_AVG,
-		"CS":  B_CAUGHT_STEALING,
-		"2B":  B_DOUBLES,
-		"G":   B_GAMES,
-		"H":   B_HITS,
-		"HR":  B_HOME_RUNS,
-		"OBP": B_ON_BASE_PCT,
-		"PA":  B_PLATE_APPS,
-		"R":   B_RUNS,
-		"RBI": B_RUNS_BATTED_IN,
-		"SLG": B_SLUGGING,
-		"SB":  B_STOLEN_BASES,
-		"K":   B_STRIKE_OUTS,
-		"3B":  B_TRIPLES,
-		"BB":  B_WALKS,
-	}
-}
-
-func mapColumnNameToPitchingStat() map[ColName]StatID {
-	return map[ColName]StatID{
-		"ER":  P_EARNED_RUNS,
-		"ERA": P_EARNED_RUN_AVERAGE,
-		"G":   P_GAMES,
-		"H":   P_HITS,
-		"HR":  P_HOME_RUNS,
-		"IP":  P_INNINGS,
-		"L":   P_LOSSES,
-		"R":   P_RUNS,
-		"GS":  P_STARTS,
-		"SO":  P_STRIKE_OUTS,
-		"BB":  P_WALKS,
-		"W":   P_WINS,
-	}
+	battingStats, pitchingStats, players := indexPlayerRows(battingRows, pitchingRows)
+	return &ZipsClient{
+		battingStats:  battingStats,
+		pitchingStats: pitchingStats,
+		players:       players,
+	}, nil
 }
 
 func mapBattingStatToColumnIndex(
@@ -106,7 +78,7 @@ func urlFetcher(url string) FetchFunction {
 	}
 }
 
-func indexBattingStats() (*map[PlayerID]StatLine, error) {
+func indexBattingStats() ([]playerRow, error) {
 	cache := NewReadThroughCache(NewFileKVStore("./cache"))
 	cacheReader, err := cache.GetAsReader(
 		urlFetcher(BATTERS_URL), BATTERS_CSV, ONE_MONTH)
@@ -115,10 +87,10 @@ func indexBattingStats() (*map[PlayerID]StatLine, error) {
 		return nil, err
 	}
 
-	return indexStats(cacheReader, mapColumnNameToBattingStat())
+	return indexStats(cacheReader, mapZipsColumnToStat(BATTING), BATTING)
 }
 
-func indexPitchingStats() (*map[PlayerID]StatLine, error) {
+func indexPitchingStats() ([]playerRow, error) {
 	cache := NewReadThroughCache(NewFileKVStore("./cache"))
 	cacheReader, err := cache.GetAsReader(
 		urlFetcher(PITCHERS_URL), PITCHERS_CSV, ONE_MONTH)
@@ -127,10 +99,10 @@ func indexPitchingStats() (*map[PlayerID]StatLine, error) {
 		return nil, err
 	}
 
-	return indexStats(cacheReader, mapColumnNameToPitchingStat())
+	return indexStats(cacheReader, mapZipsColumnToStat(PITCHING), PITCHING)
 }
 
-func indexStats(f io.Reader, columnNameToStat map[ColName]StatID) (*map[PlayerID]StatLine, error) {
+func indexStats(f io.Reader, columnNameToStat map[ColName]StatID, side string) ([]playerRow, error) {
 	r := csv.NewReader(f)
 	r.TrailingComma = true // Ok to end in trailing comma
 	recs, err := r.ReadAll()
@@ -140,8 +112,9 @@ func indexStats(f io.Reader, columnNameToStat map[ColName]StatID) (*map[PlayerID
 	}
 
 	statToColumnIndex := mapBattingStatToColumnIndex(recs[0], columnNameToStat)
+	teamIndex := findColumn(recs[0], "Team", "Tm")
 
-	statIndex := map[PlayerID]StatLine{}
+	rows := []playerRow{}
 	for i := 1; i < len(recs); i++ {
 		statLine := make(StatLine)
 		for stat, index := range statToColumnIndex {
@@ -152,8 +125,12 @@ func indexStats(f io.Reader, columnNameToStat map[ColName]StatID) (*map[PlayerID
 			}
 			statLine[stat] = Stat(stat64)
 		}
-		statIndex[PlayerID(recs[i][0])] = statLine
+		record := PlayerRecord{Name: recs[i][0], Side: side}
+		if teamIndex != -1 {
+			record.Team = recs[i][teamIndex]
+		}
+		rows = append(rows, playerRow{record: record, stats: statLine})
 	}
 
-	return &statIndex, nil
+	return rows, nil
 }
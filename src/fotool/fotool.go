@@ -4,12 +4,14 @@ import (
 	"folib"
 
 	"bufio"
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"strings"
 	"time"
 	"os"
+	"os/signal"
 )
 
 func Usage() {
@@ -22,7 +24,58 @@ func Usage() {
 	fmt.Println("https://dev.twitter.com/apps/new")
 }
 
-func loadYahooClientOrDie(key, secret, tokenFile string) *folib.YahooClient {
+// Asks the user to confirm something on stdin, e.g. before changing their
+// team on Yahoo.
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func transactOrDie(ctx context.Context, yahooclient *folib.YahooClient, league *folib.LeagueContext, action, add, drop string, faab int) {
+	var description string
+	switch action {
+	case "add":
+		description = fmt.Sprintf("Add %s", add)
+	case "drop":
+		description = fmt.Sprintf("Drop %s", drop)
+	case "adddrop":
+		description = fmt.Sprintf("Add %s and drop %s", add, drop)
+	case "claim":
+		description = fmt.Sprintf("Claim %s off waivers for $%d", add, faab)
+		if drop != "" {
+			description += fmt.Sprintf(", dropping %s", drop)
+		}
+	}
+	if (action != "drop" && add == "") || ((action == "drop" || action == "adddrop") && drop == "") {
+		log.Fatalf("Missing --add_player or --drop_player for %s", action)
+	}
+
+	if !confirm(fmt.Sprintf("%s for team %s?", description, league.MyTeamKey)) {
+		fmt.Println("Cancelled")
+		return
+	}
+
+	var transaction *folib.YahooTransaction
+	var err error
+	switch action {
+	case "add":
+		transaction, err = yahooclient.AddPlayer(ctx, league.LeagueKey, league.MyTeamKey, add)
+	case "drop":
+		transaction, err = yahooclient.DropPlayer(ctx, league.LeagueKey, league.MyTeamKey, drop)
+	case "adddrop":
+		transaction, err = yahooclient.AddDrop(ctx, league.LeagueKey, league.MyTeamKey, add, drop)
+	case "claim":
+		transaction, err = yahooclient.WaiverClaim(ctx, league.LeagueKey, league.MyTeamKey, add, drop, faab)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Transaction %s: %s\n", transaction.TransactionKey, transaction.Status)
+}
+
+func loadYahooClientOrDie(key, secret, redirectUrl, tokenFile string) *folib.YahooClient {
 	if len(key) == 0 || len(secret) == 0 {
 		fmt.Println("You must set the --consumerkey and --consumersecret flags.")
 		fmt.Println("---")
@@ -30,7 +83,49 @@ func loadYahooClientOrDie(key, secret, tokenFile string) *folib.YahooClient {
 		os.Exit(1)
 	}
 
-	return folib.NewYahooClient(key, secret, tokenFile)
+	return folib.NewYahooClientWithTokens(
+		folib.NewYahooTokenProvider(key, secret, redirectUrl, tokenFile))
+}
+
+// Where to get player stats, and how to weigh them when blending.
+type statsOptions struct {
+	season     int
+	fgTab      int
+	zipsWeight float64
+	fgWeight   float64
+}
+
+func loadStatsClientOrDie(source string, options statsOptions) folib.StatsClient {
+	switch source {
+	case "zips":
+		zipsclient, err := folib.NewZipsClient()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return zipsclient
+	case "fg":
+		fgclient, err := folib.NewFanGraphsClient(options.season, options.fgTab)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return fgclient
+	case "blend":
+		zips := loadStatsClientOrDie("zips", options)
+		// FanGraphs has the season so far, so put it on a full season pace
+		// before averaging it with ZiPS' full season projections.
+		actuals := loadStatsClientOrDie("fg", options).(*folib.FanGraphsClient)
+		fraction := actuals.SeasonFraction()
+		if fraction <= 0 {
+			log.Printf("No games played yet in %d, so just using ZiPS", options.season)
+			return zips
+		}
+		return folib.NewBlendedStatsClient(
+			folib.BlendSource{Name: "zips", Client: zips, Weight: options.zipsWeight},
+			folib.BlendSource{Name: "fg", Client: folib.NewScaledStatsClient(actuals, 1/fraction), Weight: options.fgWeight})
+	}
+
+	log.Fatalf("Unknown stats source '%s'", source)
+	return nil
 }
 
 func main() {
@@ -49,26 +144,300 @@ func main() {
 		"",
 		"A file to stash the auth token")
 
+	var redirectUrl *string = flag.String(
+		"redirect_url",
+		folib.OOB_REDIRECT,
+		"Where Yahoo sends you after authorizing. Either 'oob', to paste the code " +
+		"back in, or a local URL to listen on (e.g. http://localhost:8080/), " +
+		"which must match the app's registered redirect URI")
+
+	var gameKey *string = flag.String(
+		"game",
+		"",
+		"Yahoo game key (e.g. 328). Defaults to the current MLB season.")
+
+	var leagueKey *string = flag.String(
+		"league",
+		"",
+		"Yahoo league key (e.g. 328.l.1305). Defaults to your only league.")
+
 	var action *string = flag.String(
 		"action",
 		"optimize",
 		"What to do")
 
+	var week *int = flag.Int(
+		"week",
+		0,
+		"Which week's matchup to project (defaults to the current week), or stats to summarize (defaults to the season)")
+
+	var trials *int = flag.Int(
+		"trials",
+		10000,
+		"How many seasons to simulate")
+
+	var tradePlayers *int = flag.Int(
+		"trade_players",
+		8,
+		"How many of each team's best players to consider trading (0 for everyone)")
+
+	var maxTrades *int = flag.Int(
+		"max_trades",
+		20,
+		"The most trades to suggest")
+
+	var positions *string = flag.String(
+		"positions",
+		"",
+		"Comma-separated positions to look for free agents at. Defaults to all of them.")
+
+	var freeAgents *int = flag.Int(
+		"free_agents",
+		25,
+		"How many free agents to consider at each position")
+
+	var date *string = flag.String(
+		"date",
+		time.Now().Format("2006-01-02"),
+		"Which day to set the lineup for, or summarize stats for")
+
+	var dryRun *bool = flag.Bool(
+		"dryrun",
+		true,
+		"Only show what would change, without changing anything on Yahoo")
+
+	var maxStarts *int = flag.Int(
+		"max_starts",
+		0,
+		"The league's weekly cap on games started, for --action=stream (0 for the league's setting, if any)")
+
+	var maxInnings *float64 = flag.Float64(
+		"max_innings",
+		0,
+		"The league's weekly cap on innings pitched, for --action=stream (0 for the league's setting, if any)")
+
+	var scheduleFile *string = flag.String(
+		"schedule",
+		"",
+		"CSV of the MLB schedule (date,away,home[,away_probable,home_probable]). " +
+		"Fetched from MLB if not given")
+
+	var addPlayer *string = flag.String(
+		"add_player",
+		"",
+		"Player key to add, for --action=add, adddrop or claim")
+
+	var dropPlayer *string = flag.String(
+		"drop_player",
+		"",
+		"Player key to drop, for --action=drop, adddrop or claim")
+
+	var faabBid *int = flag.Int(
+		"faab",
+		0,
+		"How much of the free agent budget to bid, for --action=claim")
+
+	var stats *string = flag.String(
+		"stats",
+		"zips",
+		"Where to get player stats: 'zips' (projections), 'fg' (FanGraphs actuals) or 'blend' (both, with the actuals on a full season pace)")
+
+	var zipsWeight *float64 = flag.Float64(
+		"zips_weight",
+		1,
+		"How much to weigh ZiPS projections with -stats=blend")
+
+	var fgWeight *float64 = flag.Float64(
+		"fg_weight",
+		1,
+		"How much to weigh FanGraphs actuals with -stats=blend. Early in the season, they're mostly noise.")
+
+	var season *int = flag.Int(
+		"season",
+		time.Now().Year(),
+		"Which season to fetch FanGraphs stats (and the MLB schedule) for")
+
+	var fgTab *int = flag.Int(
+		"fg_tab",
+		folib.FANGRAPHS_STANDARD,
+		"Which FanGraphs leaderboard tab to fetch. It needs counting stats, which only the standard tab (0) has.")
+
+	var player *string = flag.String(
+		"player",
+		"",
+		"A player to look up")
+
+	var overridesFile *string = flag.String(
+		"overrides",
+		"player_overrides.csv",
+		"A CSV file of yahoo_key,player_id matches for players we can't match by name")
+
+	var saveOverrides *bool = flag.Bool(
+		"save_overrides",
+		false,
+		"With -action=players, rewrite the -overrides file with the players we couldn't match (commented out) appended")
+
 	flag.Parse()
 
-	if *action == "optimize" {
-		zipsclient, err := folib.NewZipsClient()
+	// Stop waiting on Yahoo if we're interrupted.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	loadFOOrDie := func() *folib.FO {
+		statsclient := loadStatsClientOrDie(*stats, statsOptions{
+			season:     *season,
+			fgTab:      *fgTab,
+			zipsWeight: *zipsWeight,
+			fgWeight:   *fgWeight,
+		})
+		yahooclient := loadYahooClientOrDie(*consumerKey, *consumerSecret, *redirectUrl, *tokenFile)
+		league, err := yahooclient.DiscoverLeagueContext(ctx, *gameKey, *leagueKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		fo := folib.NewFO(yahooclient, statsclient, league)
+		err = fo.LoadPlayerOverrides(*overridesFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return fo
+	}
+
+	loadSchedule := func(fo *folib.FO) error {
+		var schedule *folib.Schedule
+		var err error
+		if *scheduleFile != "" {
+			schedule, err = folib.LoadSchedule(*scheduleFile)
+		} else {
+			schedule, err = folib.FetchSchedule(*season)
+		}
 		if err != nil {
+			return err
+		}
+		fo.SetSchedule(schedule)
+		return nil
+	}
+	loadScheduleOrDie := func(fo *folib.FO) {
+		if err := loadSchedule(fo); err != nil {
 			log.Fatal(err)
 		}
+	}
+	// For actions which can do without the schedule, just less well.
+	loadScheduleOrWarn := func(fo *folib.FO) {
+		if err := loadSchedule(fo); err != nil {
+			log.Printf("Going without the MLB schedule: %s", err)
+		}
+	}
 
-		yahooclient := loadYahooClientOrDie(*consumerKey, *consumerSecret, *tokenFile)
-		fo := folib.NewFO(yahooclient, zipsclient)
-		fo.Optimize()
+	if *action == "optimize" {
+		fo := loadFOOrDie()
+		fo.Optimize(ctx)
+	} else if *action == "players" {
+		fo := loadFOOrDie()
+		err := fo.ReportUnmatchedPlayers(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *saveOverrides {
+			err = fo.SavePlayerOverrides(*overridesFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+	} else if *action == "matchup" {
+		fo := loadFOOrDie()
+		loadScheduleOrWarn(fo)
+		projection, err := fo.ProjectMatchup(ctx, *week)
+		if err != nil {
+			log.Fatal(err)
+		}
+		folib.PrintMatchupProjection(projection)
+	} else if *action == "season" {
+		fo := loadFOOrDie()
+		projection, err := fo.SimulateSeason(ctx, time.Now())
+		if err != nil {
+			log.Fatal(err)
+		}
+		folib.PrintSeasonProjection(projection)
+	} else if *action == "standings" {
+		fo := loadFOOrDie()
+		simulation, err := fo.SimulateStandings(ctx, time.Now(), *trials)
+		if err != nil {
+			log.Fatal(err)
+		}
+		folib.PrintStandingsSimulation(simulation)
+	} else if *action == "trades" {
+		fo := loadFOOrDie()
+		rosters, err := fo.LeagueRosters(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		trades, err := fo.FindTrades(ctx, rosters, folib.TradeSearchOptions{
+			PlayersPerTeam: *tradePlayers,
+			MaxResults:     *maxTrades,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		folib.PrintTrades(trades)
+	} else if *action == "pickups" {
+		fo := loadFOOrDie()
+		positionList := []string{}
+		if *positions != "" {
+			positionList = strings.Split(*positions, ",")
+		}
+		recommendations, err := fo.RecommendPickups(ctx, positionList, *freeAgents, 5)
+		if err != nil {
+			log.Fatal(err)
+		}
+		folib.PrintPickups(recommendations)
+	} else if *action == "stream" {
+		fo := loadFOOrDie()
+		loadScheduleOrDie(fo)
+		plan, err := fo.PlanStreamers(ctx, *date, folib.StreamingOptions{
+			FreeAgents: *freeAgents,
+			MaxStarts:  *maxStarts,
+			MaxInnings: *maxInnings,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		folib.PrintStreamers(plan)
+	} else if *action == "lineup" {
+		fo := loadFOOrDie()
+		// To know whose teams are off today.
+		loadScheduleOrWarn(fo)
+		changes, err := fo.OptimizeLineup(ctx, *date, *dryRun)
+		if err != nil {
+			log.Fatal(err)
+		}
+		folib.PrintLineupChanges(changes)
+		if *dryRun && len(changes) > 0 {
+			fmt.Println("(dry run, rerun with --dryrun=false to make these changes)")
+		}
+	} else if *action == "week" {
+		fo := loadFOOrDie()
+		loadScheduleOrWarn(fo)
+		plan, err := fo.OptimizeWeek(ctx, *date, *dryRun)
+		if err != nil {
+			log.Fatal(err)
+		}
+		folib.PrintWeekPlan(plan)
+		if *dryRun {
+			fmt.Println("(dry run, rerun with --dryrun=false to make these changes)")
+		}
+	} else if *action == "add" || *action == "drop" || *action == "adddrop" || *action == "claim" {
+		yahooclient := loadYahooClientOrDie(*consumerKey, *consumerSecret, *redirectUrl, *tokenFile)
+		league, err := yahooclient.DiscoverLeagueContext(ctx, *gameKey, *leagueKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+		transactOrDie(ctx, yahooclient, league, *action, *addPlayer, *dropPlayer, *faabBid)
 	} else if *action == "summarize" {
-		yahooclient := loadYahooClientOrDie(*consumerKey, *consumerSecret, *tokenFile)
+		yahooclient := loadYahooClientOrDie(*consumerKey, *consumerSecret, *redirectUrl, *tokenFile)
 
-		games, err := yahooclient.GetGames()
+		games, err := yahooclient.GetGames(ctx)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -82,7 +451,7 @@ func main() {
 			log.Fatal("I should ask you which game here and set gameidx...")
 		}
 
-		leagues, err := yahooclient.GetLeagues(games[gameidx].GameKey)
+		leagues, err := yahooclient.GetLeagues(ctx, games[gameidx].GameKey)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -97,7 +466,7 @@ func main() {
 			log.Fatal("I should ask you which game here and set leagueidx...")
 		}
 
-		teams, err := yahooclient.GetTeams(leagues[leagueidx].LeagueKey)
+		teams, err := yahooclient.GetTeams(ctx, leagues[leagueidx].LeagueKey)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -112,7 +481,7 @@ func main() {
 			fmt.Printf("%d. %s %s\n", i, team.Name, icon)
 		}
 
-		players, err := yahooclient.GetRoster(teams[teamidx].TeamKey)
+		players, err := yahooclient.GetRoster(ctx, teams[teamidx].TeamKey)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -122,13 +491,23 @@ func main() {
 			allPlayerKeys = append(allPlayerKeys, player.PlayerKey)
 		}
 
-		statsByPlayer, err := yahooclient.GetStats(allPlayerKeys)
+		// Season stats, unless a week or a day is asked for.
+		scope := folib.StatsScope{Week: *week}
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "date" {
+				scope = folib.StatsScope{Date: *date}
+			}
+		})
+		statsByPlayer, err := yahooclient.GetStats(ctx, allPlayerKeys, scope)
+		if _, partial := err.(*folib.StatsError); partial {
+			// Show what we did get.
+			log.Print(err)
+		} else if err != nil {
+			log.Fatal(err)
+		}
 
 		for i, player := range(players) {
 			metadata := ""
-			if err != nil {
-				log.Fatal(err)
-			}
 
 			statline := statsByPlayer[player.PlayerKey]
 			if player.PositionType == "P" {
@@ -156,7 +535,7 @@ func main() {
 		}
 		
 	} else if *action == "interactive" {
-		yahooclient := loadYahooClientOrDie(*consumerKey, *consumerSecret, *tokenFile)
+		yahooclient := loadYahooClientOrDie(*consumerKey, *consumerSecret, *redirectUrl, *tokenFile)
 
 		for {
 			fmt.Print("> ");
@@ -192,7 +571,7 @@ func main() {
 					break
 				}
 				fmt.Printf("Fetching yahoo url '%s'\n", inputParts[1])
-				resp, err := yahooclient.Get(inputParts[1])
+				resp, err := yahooclient.Get(ctx, inputParts[1])
 				if err != nil {
 					fmt.Printf("ERROR: %s\n", err.Error())
 				} else {
@@ -201,10 +580,15 @@ func main() {
 			}
 		}
 	} else if *action == "fg" {
-		_, err := folib.NewFanGraphsClient()
+		fgclient, err := folib.NewFanGraphsClient(*season, *fgTab)
 		if err != nil {
 			log.Fatal(err)
 		}
 
+		if len(*player) > 0 {
+			statline := fgclient.GetStatLine(folib.PlayerID(*player))
+			fmt.Printf("%s: %s\n", *player, folib.FormatBattingStats(statline))
+			fmt.Printf("%s: %s\n", *player, folib.FormatPitchingStats(statline))
+		}
 	}
 }